@@ -1,122 +1,321 @@
 package main
 
 import (
+	"awesome-clean-arch/internal/audit"
+	"awesome-clean-arch/internal/audit/db/mysql"
 	"awesome-clean-arch/internal/auth"
 	"awesome-clean-arch/internal/auth/db/mysql"
 	"awesome-clean-arch/internal/config"
-	"awesome-clean-arch/internal/profile"
+	"awesome-clean-arch/internal/domainevents"
+	"awesome-clean-arch/internal/events"
+	"awesome-clean-arch/internal/graphqlapi"
+	"awesome-clean-arch/internal/handlers"
+	"awesome-clean-arch/internal/idempotency"
+	"awesome-clean-arch/internal/idempotency/db/mysql"
+	"awesome-clean-arch/internal/outbox"
+	"awesome-clean-arch/internal/outbox/db/mysql"
 	"awesome-clean-arch/internal/profile/db/mysql"
-	"awesome-clean-arch/internal/user"
+	"awesome-clean-arch/internal/purge"
 	"awesome-clean-arch/internal/user/db/mysql"
-	"awesome-clean-arch/internal/user_data"
 	"awesome-clean-arch/internal/user_data/db/mysql"
+	"awesome-clean-arch/internal/webhook"
+	"awesome-clean-arch/internal/webhook/db/mysql"
 	"awesome-clean-arch/pkg/client/mysql"
+	"awesome-clean-arch/pkg/eventbus"
+	"awesome-clean-arch/pkg/httpsign"
 	"awesome-clean-arch/pkg/logging"
+	"awesome-clean-arch/pkg/ratelimit"
 	"context"
-	"fmt"
 	"github.com/julienschmidt/httprouter"
-	"net"
 	"net/http"
 	"os"
-	"path"
-	"path/filepath"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 )
 
+const shutdownTimeout = 30 * time.Second
+
 func main() {
-	logger := logging.GetLogger()
+	if len(os.Args) > 2 && os.Args[1] == "config" && os.Args[2] == "check" {
+		os.Exit(checkConfig(os.Args[3:]))
+	}
+	if len(os.Args) > 2 && os.Args[1] == "openapi" && os.Args[2] == "check" {
+		os.Exit(checkOpenAPI(os.Args[3:]))
+	}
 
-	logger.Infoln("Create router...")
-	router := httprouter.New()
-	logger.Infoln("...created")
+	logger := logging.GetLogger()
 
 	cfg := config.GetConfig()
 
-	mysqlClient, err := mysql.NewClient(context.TODO(), 3, cfg.Storage)
+	ctx, cancel := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+
+	if err := logging.SetLevel(cfg.Log.Level); err != nil {
+		logger.Fatal(err)
+	}
+	config.Subscribe(config.SectionLog, func(cfg *config.Config) {
+		if err := logging.SetLevel(cfg.Log.Level); err != nil {
+			logger.Error(err)
+		}
+	})
+
+	cors := handlers.NewCORS(cfg.CORS)
+	config.Subscribe(config.SectionCORS, func(cfg *config.Config) {
+		cors.Update(cfg.CORS)
+	})
+
+	config.Watch(ctx, 5*time.Second)
+
+	mysqlClient, err := mysql.NewClient(ctx, 3, cfg.Storage)
 	if err != nil {
 		logger.Fatalf("%s", err)
 	}
 
-	logger.Infoln("Create authRepository...")
-	authRepository := mysql_auth.NewMySQLRepository(mysqlClient, logger)
+	logger.Infoln("Create auditRepository...")
+	auditRepository := mysql_audit.NewMySQLRepository(mysqlClient, logger)
+	auditRecorder := audit.NewRecorder(auditRepository, logger)
 	logger.Infoln("...created")
 
-	authHandler := auth.NewHandler(logger, authRepository)
-	authHandler.Register(router)
+	logger.Infoln("Create webhookRepository...")
+	webhookRepository := mysql_webhook.NewMySQLRepository(mysqlClient, logger)
 	logger.Infoln("...created")
 
-	logger.Infoln("Create userRepository...")
-	userRepository := mysql_user.NewMySQLRepository(mysqlClient, logger)
+	webhookDispatcher := webhook.NewDispatcher(webhookRepository, webhook.Options{
+		MaxAttempts:  cfg.Webhooks.MaxAttempts,
+		Backoff:      cfg.Webhooks.Backoff,
+		MaxBackoff:   cfg.Webhooks.MaxBackoff,
+		Timeout:      cfg.Webhooks.Timeout,
+		PollInterval: cfg.Webhooks.PollInterval,
+		Concurrency:  cfg.Webhooks.Concurrency,
+	}, logger)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		webhookDispatcher.Run(ctx)
+	}()
+
+	logger.Infoln("Create outboxRepository...")
+	outboxRepository := mysql_outbox.NewMySQLRepository(mysqlClient, logger)
 	logger.Infoln("...created")
 
-	logger.Infoln("Create userHandler...")
-	userHandler := user.NewHandler(logger, userRepository)
-	userHandler.Register(router)
+	// Every instance reads the outbox, so its subscribers see the changes
+	// made through the others too.
+	eventLog := events.NewLog(cfg.Events.BufferSize)
+	eventFeed := events.NewFeed(outboxRepository, eventLog, events.FeedOptions{
+		PollInterval: cfg.Events.PollInterval,
+		BatchSize:    cfg.Outbox.BatchSize,
+		GapTimeout:   cfg.Events.GapTimeout,
+	}, logger)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		eventFeed.Run(ctx)
+	}()
+
+	outboxPurge := purge.NewJob(cfg.Outbox.Retention, 10*time.Minute, logger)
+	outboxPurge.Add("published outbox messages", outboxRepository)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		outboxPurge.Run(ctx)
+	}()
+
+	logger.Infoln("Create authRepository...")
+	authRepository := audit.NewAuthRepository(mysql_auth.NewMySQLRepository(mysqlClient, logger), auditRecorder)
 	logger.Infoln("...created")
 
-	logger.Infoln("Create profileRepository...")
-	profileRepository := mysql_profile.NewMySQLRepository(mysqlClient, logger)
+	usageTracker := auth.NewUsageTracker(authRepository, 30*time.Second, logger)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		usageTracker.Run(ctx)
+	}()
+
+	tokenService, err := auth.NewTokenService(cfg.JWT)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	signatureVerifier := httpsign.NewVerifier(cfg.Signing.ClockSkew, httpsign.NewMemoryNonceStore())
+	authenticator := auth.NewAuthenticator(authRepository, usageTracker, tokenService, signatureVerifier, logger)
+
+	rateLimiter := handlers.NewRateLimiter(ratelimit.NewMemoryLimiter(10*time.Minute), cfg.RateLimit, auth.RateLimitKey, logger)
+	config.Subscribe(config.SectionRateLimit, func(cfg *config.Config) {
+		rateLimiter.Update(cfg.RateLimit)
+	})
+
+	idempotencyRepository := mysql_idempotency.NewMySQLRepository(mysqlClient, logger)
+	idempotencyStore := idempotency.NewStore(idempotencyRepository, cfg.Idempotency.TTL, logger)
+	idempotencyPurge := purge.NewJob(0, 10*time.Minute, logger)
+	idempotencyPurge.Add("expired idempotency keys", idempotencyRepository)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		idempotencyPurge.Run(ctx)
+	}()
+
+	logger.Infoln("Create router...")
+	router := httprouter.New()
+	api := handlers.NewRouter(router,
+		handlers.RequestID(),
+		handlers.AccessLog(logger),
+		handlers.Recovery(logger),
+		handlers.BodyLimit(cfg.HTTP.MaxBodySize),
+		authenticator.Middleware(),
+		rateLimiter.Middleware(),
+		idempotencyStore.Middleware(),
+		handlers.Timeout(cfg.HTTP.RequestTimeout),
+	)
 	logger.Infoln("...created")
 
-	logger.Infoln("Create profileHandler...")
-	profileHandler := profile.NewHandler(logger, profileRepository)
-	profileHandler.Register(router)
+	bus := eventbus.New(logger)
+	domainevents.Subscribe(bus, authRepository)
+
+	if cfg.Outbox.Relay {
+		relay := outbox.NewRelay(outboxRepository, outbox.Options{
+			PollInterval: cfg.Outbox.PollInterval,
+			BatchSize:    cfg.Outbox.BatchSize,
+			Backoff:      cfg.Outbox.Backoff,
+			MaxBackoff:   cfg.Outbox.MaxBackoff,
+			MaxAttempts:  cfg.Outbox.MaxAttempts,
+		}, logger)
+		if cfg.Outbox.File != "" {
+			fileSink, err := outbox.NewFileSink(cfg.Outbox.File)
+			if err != nil {
+				logger.Fatal(err)
+			}
+			defer fileSink.Close()
+			relay.Add("file", fileSink)
+		}
+		relay.Add("webhooks", outbox.SinkFunc(func(ctx context.Context, m outbox.Message) error {
+			return webhookDispatcher.Enqueue(ctx, events.FromMessage(m))
+		}))
+		relay.Add("domain events", domainevents.NewSink(bus))
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			relay.Run(ctx)
+		}()
+	}
+
+	logger.Infoln("Create userRepository...")
+	userRepository := audit.NewUserRepository(mysql_user.NewMySQLRepository(mysqlClient, outboxRepository, logger), auditRecorder)
+	logger.Infoln("...created")
+
+	logger.Infoln("Create profileRepository...")
+	profileRepository := audit.NewProfileRepository(mysql_profile.NewMySQLRepository(mysqlClient, outboxRepository, logger), auditRecorder)
 	logger.Infoln("...created")
 
 	logger.Infoln("Create userDataRepository...")
-	userDataRepository := mysql_user_data.NewMySQLRepository(mysqlClient, logger)
+	userDataRepository := audit.NewUserDataRepository(mysql_user_data.NewMySQLRepository(mysqlClient, outboxRepository, logger), auditRecorder)
 	logger.Infoln("...created")
 
-	logger.Infoln("Create userDataHandler...")
-	userDataHandler := user_data.NewHandler(logger, userDataRepository)
-	userDataHandler.Register(router)
+	if cfg.Deletion.Retention > 0 {
+		purgeJob := purge.NewJob(cfg.Deletion.Retention, cfg.Deletion.PurgeInterval, logger)
+		purgeJob.Add("deleted profiles", profileRepository)
+		purgeJob.Add("deleted users", userRepository)
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			purgeJob.Run(ctx)
+		}()
+	}
+
+	logger.Infoln("Create handlers...")
+	repos := repositories{
+		auth:     authRepository,
+		user:     userRepository,
+		profile:  profileRepository,
+		userData: userDataRepository,
+		audit:    auditRepository,
+		webhook:  webhookRepository,
+	}
+	mountHandlers(api, logger, repos, tokenService, cfg.API.Deprecated, graphqlapi.Limits{
+		MaxDepth:      cfg.GraphQL.MaxDepth,
+		MaxComplexity: cfg.GraphQL.MaxComplexity,
+	}, eventLog, cfg.Events.Heartbeat, bus)
+	for _, route := range api.Routes() {
+		if route.Doc == nil {
+			logger.Warnf("route %s %s is missing from the OpenAPI specification", route.Method, route.Path)
+		}
+	}
 	logger.Infoln("...created")
 
+	stopGRPC := func() {}
+	if cfg.GRPC.Enabled {
+		stopGRPC = startGRPC(ctx, cfg, grpcServices(logger, repos), authenticator)
+	}
+
 	logger.Infoln("Start router...")
-	start(router, cfg)
-	logger.Infoln("...started")
+	start(ctx, cors.Wrap(router), cfg, eventLog.Close)
+	stopGRPC()
+
+	cancel()
+	workers.Wait()
+	bus.Wait()
 }
 
-func start(router *httprouter.Router, cfg *config.Config) {
+// start serves handler until the process is signalled. onShutdown is called
+// when the shutdown begins, to end long-lived requests Shutdown would wait for.
+func start(ctx context.Context, handler http.Handler, cfg *config.Config, onShutdown ...func()) {
 	logger := logging.GetLogger()
 	logger.Infoln("Start application")
 
-	//listenAddr := flag.String("port", ":8080", "the server address")
-	//flag.Parse()
+	listener, err := newListener(cfg.Listen)
+	if err != nil {
+		logger.Fatal(err)
+	}
 
-	var listener net.Listener
-	var listenErr error
+	server := &http.Server{
+		Handler:      handler,
+		WriteTimeout: 30 * time.Second,
+		ReadTimeout:  15 * time.Second,
+	}
+	for _, fn := range onShutdown {
+		server.RegisterOnShutdown(fn)
+	}
 
-	if cfg.Listen.Type == "sock" {
-		logger.Infoln("Detect app path")
-		appDir, err := filepath.Abs(filepath.Dir(os.Args[0]))
+	idle := make(chan struct{})
+	go func() {
+		shutdownOnSignal(server)
+		close(idle)
+	}()
+
+	if cfg.Listen.TLS.Enabled {
+		tlsConfig, err := newTLSConfig(ctx, cfg.Listen.TLS)
 		if err != nil {
 			logger.Fatal(err)
 		}
+		server.TLSConfig = tlsConfig
 
-		logger.Infoln("Create socket")
-		socketPath := path.Join(appDir, "app.sock")
-
-		logger.Infoln("Listen unix socket")
-		listener, listenErr = net.Listen("unix", socketPath)
-		logger.Infof("Server is listening on unix socket :%s", socketPath)
+		logger.Info("Serve HTTPS")
+		err = server.ServeTLS(listener, "", "")
 	} else {
-		logger.Info("Listen tcp")
-		//listener, listenErr = net.Listen("tcp", *listenAddr)
-		//logger.Infoln("The server running on port", *listenAddr)
-		listener, listenErr = net.Listen("tcp", fmt.Sprintf("%s:%s", cfg.Listen.BindIP, cfg.Listen.Port))
-		logger.Infof("Server is listening on %s:%s", cfg.Listen.BindIP, cfg.Listen.Port)
+		err = server.Serve(listener)
 	}
 
-	if listenErr != nil {
-		logger.Fatal(listenErr)
+	if err != http.ErrServerClosed {
+		logger.Fatal(err)
 	}
+	<-idle
+	logger.Info("Server stopped")
+}
 
-	server := &http.Server{
-		Handler:      router,
-		WriteTimeout: 30 * time.Second,
-		ReadTimeout:  15 * time.Second,
-	}
+// shutdownOnSignal lets in-flight requests finish before the process exits, so
+// a restart behind systemd socket activation does not drop connections.
+func shutdownOnSignal(server *http.Server) {
+	logger := logging.GetLogger()
 
-	logger.Fatal(server.Serve(listener))
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-stop
+
+	logger.Infof("%s received, shutting down", sig)
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		logger.Error(err)
+	}
 }
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"awesome-clean-arch/internal/config"
+	"flag"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+)
+
+// checkConfig implements "config check": it loads and validates the
+// configuration and prints the effective values with secrets masked.
+func checkConfig(args []string) int {
+	fs := flag.NewFlagSet("config check", flag.ExitOnError)
+
+	cfg, err := config.Load(fs, args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	masked := cfg.Masked()
+	enc := yaml.NewEncoder(os.Stdout)
+	enc.SetIndent(2)
+	if err = enc.Encode(&masked); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if err = cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "\ninvalid configuration:\n%s\n", err)
+		return 1
+	}
+
+	fmt.Fprintln(os.Stderr, "\nconfiguration is valid")
+	return 0
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"awesome-clean-arch/internal/auth"
+	"awesome-clean-arch/internal/config"
+	"awesome-clean-arch/internal/grpcapi"
+	"awesome-clean-arch/pkg/logging"
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"net"
+	"time"
+)
+
+// startGRPC serves services on the gRPC port until the returned function is
+// called, which waits up to shutdownTimeout for running calls.
+func startGRPC(ctx context.Context, cfg *config.Config, services []grpcapi.Service, authenticator *auth.Authenticator) func() {
+	logger := logging.GetLogger()
+
+	scopes := grpcapi.Scopes(services...)
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(
+			grpcapi.RequestID(),
+			grpcapi.Logging(logger),
+			grpcapi.Recovery(logger),
+			authenticator.UnaryInterceptor(scopes),
+		),
+		grpc.ChainStreamInterceptor(
+			grpcapi.StreamRequestID(),
+			grpcapi.StreamLogging(logger),
+			grpcapi.StreamRecovery(logger),
+			authenticator.StreamInterceptor(scopes),
+		),
+	}
+	if cfg.Listen.TLS.Enabled {
+		tlsConfig, err := newTLSConfig(ctx, cfg.Listen.TLS)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	server, health := grpcapi.NewServer(services, cfg.GRPC.Reflection, opts...)
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(cfg.Listen.BindIP, cfg.GRPC.Port))
+	if err != nil {
+		logger.Fatal(err)
+	}
+	logger.Infof("gRPC server is listening on %s", listener.Addr())
+
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			logger.Fatal(err)
+		}
+	}()
+
+	return func() {
+		health.Shutdown()
+
+		stopped := make(chan struct{})
+		go func() {
+			server.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(shutdownTimeout):
+			server.Stop()
+		}
+		logger.Info("gRPC server stopped")
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"awesome-clean-arch/internal/config"
+	"awesome-clean-arch/pkg/logging"
+	"awesome-clean-arch/pkg/systemd"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+func newListener(cfg config.ListenConfig) (net.Listener, error) {
+	logger := logging.GetLogger()
+
+	activated, err := systemd.Listeners()
+	if err != nil {
+		return nil, fmt.Errorf("systemd socket activation: %w", err)
+	}
+	if len(activated) > 0 {
+		for _, l := range activated[1:] {
+			l.Close()
+		}
+		logger.Infof("Server is listening on systemd activated socket %s", activated[0].Addr())
+		return activated[0], nil
+	}
+
+	if cfg.Type == "sock" {
+		return listenUnix(cfg)
+	}
+
+	logger.Info("Listen tcp")
+	listener, err := net.Listen("tcp", net.JoinHostPort(cfg.BindIP, cfg.Port))
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("Server is listening on %s", listener.Addr())
+	return listener, nil
+}
+
+func listenUnix(cfg config.ListenConfig) (net.Listener, error) {
+	logger := logging.GetLogger()
+
+	socketPath := cfg.SocketPath
+	if socketPath == "" {
+		logger.Infoln("Detect app path")
+		appDir, err := filepath.Abs(filepath.Dir(os.Args[0]))
+		if err != nil {
+			return nil, err
+		}
+		socketPath = filepath.Join(appDir, "app.sock")
+	}
+
+	if err := removeStaleSocket(socketPath); err != nil {
+		return nil, err
+	}
+
+	logger.Infoln("Listen unix socket")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = setSocketPermissions(socketPath, cfg.SocketMode, cfg.SocketGroup); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	logger.Infof("Server is listening on unix socket %s", socketPath)
+	return listener, nil
+}
+
+// removeStaleSocket deletes a socket file left behind by a crashed process.
+// It refuses to touch regular files and sockets that still accept connections.
+func removeStaleSocket(socketPath string) error {
+	fi, err := os.Lstat(socketPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if fi.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", socketPath)
+	}
+
+	conn, err := net.DialTimeout("unix", socketPath, time.Second)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("socket %s is in use by another process", socketPath)
+	}
+
+	logging.GetLogger().Warnf("Removing stale socket %s", socketPath)
+	return os.Remove(socketPath)
+}
+
+func setSocketPermissions(socketPath, mode, group string) error {
+	perm, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil {
+		return fmt.Errorf("socket mode: %w", err)
+	}
+	if err = os.Chmod(socketPath, os.FileMode(perm)); err != nil {
+		return err
+	}
+
+	if group == "" {
+		return nil
+	}
+
+	gid, err := strconv.Atoi(group)
+	if err != nil {
+		g, err := user.LookupGroup(group)
+		if err != nil {
+			return err
+		}
+		if gid, err = strconv.Atoi(g.Gid); err != nil {
+			return err
+		}
+	}
+	return os.Lchown(socketPath, -1, gid)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"awesome-clean-arch/internal/audit"
+	"awesome-clean-arch/internal/auth"
+	"awesome-clean-arch/internal/config"
+	"awesome-clean-arch/internal/domainevents"
+	"awesome-clean-arch/internal/events"
+	"awesome-clean-arch/internal/graphqlapi"
+	"awesome-clean-arch/internal/grpcapi"
+	"awesome-clean-arch/internal/handlers"
+	"awesome-clean-arch/internal/openapi"
+	"awesome-clean-arch/internal/profile"
+	"awesome-clean-arch/internal/user"
+	"awesome-clean-arch/internal/user_data"
+	"awesome-clean-arch/internal/webhook"
+	"awesome-clean-arch/pkg/eventbus"
+	"awesome-clean-arch/pkg/logging"
+	"time"
+)
+
+var apiInfo = openapi.Info{
+	Title: "awesome-clean-arch API",
+	Description: "The validation rules of the schemas are enforced from v2 on, where a request breaking them is " +
+		"rejected with 422. v1 keeps accepting such requests, except for imports.",
+	Version: "1.0.0",
+}
+
+// repositories are the dependencies of the HTTP handlers.
+type repositories struct {
+	auth     auth.Repository
+	user     user.Repository
+	profile  profile.Repository
+	userData user_data.Repository
+	audit    audit.Repository
+	webhook  webhook.Repository
+}
+
+// mountHandlers registers every HTTP handler on api. Domain handlers are
+// served under a version prefix, versions share the repositories and differ
+// only in their representations. The token endpoints are only served when
+// tokens is not nil.
+func mountHandlers(api handlers.Router, logger *logging.Logger, repos repositories, tokens *auth.TokenService, deprecated []config.DeprecatedVersion, graphql graphqlapi.Limits, feed *events.Log, heartbeat time.Duration, bus *eventbus.Bus) {
+	handlers.Mount(versionGroup(api, "v1", logger, deprecated),
+		auth.NewHandler(logger, repos.auth),
+		user.NewHandler(logger, repos.user),
+		profile.NewHandler(logger, repos.profile),
+		user_data.NewHandler(logger, repos.userData),
+		audit.NewHandler(logger, repos.audit),
+		webhook.NewHandler(logger, repos.webhook),
+	)
+	handlers.Mount(versionGroup(api, "v2", logger, deprecated).With(handlers.StrictValidation),
+		auth.NewHandler(logger, repos.auth),
+		user.NewHandler(logger, repos.user),
+		profile.NewHandlerV2(logger, repos.profile),
+		user_data.NewHandler(logger, repos.userData),
+		audit.NewHandler(logger, repos.audit),
+		webhook.NewHandler(logger, repos.webhook),
+	)
+
+	// Token issuance, the JWKS and the specification describe every version,
+	// so they stay at the root, like the unversioned GraphQL schema, change
+	// feed and event bus statistics.
+	if tokens != nil {
+		handlers.Mount(api, auth.NewTokenHandler(logger, tokens))
+	}
+	handlers.Mount(api, graphqlapi.NewHandler(logger, graphqlapi.Repositories{
+		Users:    repos.user,
+		Profiles: repos.profile,
+		UserData: repos.userData,
+		Auth:     repos.auth,
+	}, graphql))
+	handlers.Mount(api, events.NewHandler(logger, feed, heartbeat))
+	handlers.Mount(api, domainevents.NewHandler(logger, bus))
+	handlers.Mount(api, openapi.NewHandler(logger, apiInfo, api))
+}
+
+func versionGroup(api handlers.Router, version string, logger *logging.Logger, deprecated []config.DeprecatedVersion) handlers.Router {
+	for _, d := range deprecated {
+		if d.Version == version {
+			return handlers.Deprecate(api, "/"+version, handlers.Deprecation{
+				Version: d.Version,
+				Since:   d.Since,
+				Sunset:  d.Sunset,
+				Link:    d.Link,
+			}, logger)
+		}
+	}
+	return api.Group("/" + version)
+}
+
+// grpcServices are the gRPC counterparts of the v1 handlers.
+func grpcServices(logger *logging.Logger, repos repositories) []grpcapi.Service {
+	return []grpcapi.Service{
+		user.NewGRPCService(logger, repos.user),
+		profile.NewGRPCService(logger, repos.profile),
+		user_data.NewGRPCService(logger, repos.userData),
+		auth.NewGRPCService(logger, repos.auth),
+	}
+}
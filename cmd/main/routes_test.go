@@ -0,0 +1,32 @@
+package main
+
+import (
+	"awesome-clean-arch/internal/auth"
+	"awesome-clean-arch/internal/events"
+	"awesome-clean-arch/internal/graphqlapi"
+	"awesome-clean-arch/internal/handlers"
+	"awesome-clean-arch/internal/openapi"
+	"awesome-clean-arch/pkg/eventbus"
+	"awesome-clean-arch/pkg/logging"
+	"github.com/julienschmidt/httprouter"
+	"testing"
+	"time"
+)
+
+func TestEveryRouteIsDocumented(t *testing.T) {
+	api := handlers.NewRouter(httprouter.New())
+	mountHandlers(api, logging.GetLogger(), repositories{}, &auth.TokenService{}, nil, graphqlapi.Limits{}, events.NewLog(1), time.Second, eventbus.New(logging.GetLogger()))
+
+	routes := api.Routes()
+	if len(routes) == 0 {
+		t.Fatal("no routes were mounted")
+	}
+
+	doc, missing := openapi.Build(apiInfo, routes)
+	for _, route := range missing {
+		t.Errorf("%s %s is missing from the specification", route.Method, route.Path)
+	}
+	if len(doc.Paths) == 0 {
+		t.Error("the specification has no paths")
+	}
+}
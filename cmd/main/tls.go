@@ -0,0 +1,57 @@
+package main
+
+import (
+	"awesome-clean-arch/internal/config"
+	"awesome-clean-arch/pkg/certs"
+	"awesome-clean-arch/pkg/logging"
+	"context"
+	"crypto/tls"
+	"time"
+)
+
+const certReloadInterval = 10 * time.Second
+
+func newTLSConfig(ctx context.Context, cfg config.TLSConfig) (*tls.Config, error) {
+	logger := logging.GetLogger()
+
+	reloader, err := certs.NewReloader(cfg.CertFile, cfg.KeyFile, cfg.ClientCAFile, logger)
+	if err != nil {
+		return nil, err
+	}
+	reloader.Watch(ctx, certReloadInterval)
+
+	minVersion, err := config.TLSVersion(cfg.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	cipherSuites, err := config.TLSCipherSuites(cfg.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:     minVersion,
+		CipherSuites:   cipherSuites,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	if cfg.ClientCAFile != "" {
+		clientAuth, err := config.TLSClientAuth(cfg.ClientAuth)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.ClientAuth = clientAuth
+		tlsConfig.ClientCAs = reloader.ClientCAs()
+
+		// Hand out a fresh config per handshake so a reloaded CA bundle is used.
+		tlsConfig.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			c := tlsConfig.Clone()
+			c.GetConfigForClient = nil
+			c.ClientCAs = reloader.ClientCAs()
+			return c, nil
+		}
+		logger.Infof("Mutual TLS enabled, client auth: %s", cfg.ClientAuth)
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"awesome-clean-arch/internal/config"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// secrets seals a plain JSON object of secrets into an encrypted file
+// readable by the "file" secret provider.
+func main() {
+	keyFile := flag.String("key-file", "", "path to the hex encoded 32 byte key (or APP_SECRETS_KEY)")
+	in := flag.String("in", "", "path to a JSON object of secrets")
+	out := flag.String("out", "secrets.enc", "path to the encrypted output file")
+	genKey := flag.Bool("gen-key", false, "print a new random key and exit")
+	flag.Parse()
+
+	if err := run(*keyFile, *in, *out, *genKey); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(keyFile, in, out string, genKey bool) error {
+	if genKey {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return err
+		}
+		fmt.Println(hex.EncodeToString(key))
+		return nil
+	}
+
+	encoded := os.Getenv("APP_SECRETS_KEY")
+	if keyFile != "" {
+		b, err := os.ReadFile(keyFile)
+		if err != nil {
+			return err
+		}
+		encoded = string(b)
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return err
+	}
+
+	plain, err := os.ReadFile(in)
+	if err != nil {
+		return err
+	}
+	secrets := make(map[string]string)
+	if err = json.Unmarshal(plain, &secrets); err != nil {
+		return err
+	}
+
+	sealed, err := config.SealSecrets(key, secrets)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(out, sealed, 0600)
+}
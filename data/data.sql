@@ -1,4 +1,4 @@
-INSERT INTO `auth` VALUES (1,'www-dfq92-sqfwf'),(2,'ffff-2918-xcas');
-INSERT INTO `user` VALUES (1,'test'),(2,'admin'),(3,'guest');
-INSERT INTO user_data VALUES (1,'Gymnasium #179 in Kyiv'),(2,'Lyceum #227'),(3,'Medical Gymnasium #33 in Kyiv');
-INSERT INTO user_profile VALUES (1,'Olexander','Shkilnyy','+38050123455','Sibirskay St. 2','Kyiv'),(2,'Dmytro','Arbuzov','+38065133223','Bila St. 4','Kharkiv'),(3,'Vasyl','Shpak','+38055221166','Severna St. 5','Zhytomyr');
+INSERT INTO `user` (id, username) VALUES (1,'test'),(2,'admin'),(3,'guest');
+INSERT INTO `auth` (id, api_key, scopes, user_id) VALUES (1,'www-dfq92-sqfwf','auth:admin',2),(2,'ffff-2918-xcas','users:read,profiles:read,user_data:read',1);
+INSERT INTO user_data (user_id, school) VALUES (1,'Gymnasium #179 in Kyiv'),(2,'Lyceum #227'),(3,'Medical Gymnasium #33 in Kyiv');
+INSERT INTO user_profile (user_id, first_name, last_name, phone, address, city) VALUES (1,'Olexander','Shkilnyy','+38050123455','Sibirskay St. 2','Kyiv'),(2,'Dmytro','Arbuzov','+38065133223','Bila St. 4','Kharkiv'),(3,'Vasyl','Shpak','+38055221166','Severna St. 5','Zhytomyr');
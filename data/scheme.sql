@@ -1,13 +1,31 @@
-CREATE TABLE `auth` (
+CREATE TABLE `user` (
   `id` bigint(20) NOT NULL AUTO_INCREMENT,
-  `api_key` varchar(32) NOT NULL,
-  PRIMARY KEY (`id`)
+  `username` varchar(64) NOT NULL UNIQUE,
+  `version` int NOT NULL DEFAULT 1,
+  `deleted_at` datetime DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_user_deleted_at` (`deleted_at`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8;
 
-CREATE TABLE `user` (
+CREATE TABLE `auth` (
   `id` bigint(20) NOT NULL AUTO_INCREMENT,
-  `username` varchar(64) NOT NULL UNIQUE,
-  PRIMARY KEY (`id`)
+  `api_key` varchar(32) NOT NULL,
+  `scopes` varchar(255) NOT NULL DEFAULT '',
+  `signing_secret` varchar(64) DEFAULT NULL,
+  `rate_limit` double DEFAULT NULL,
+  `rate_burst` int DEFAULT NULL,
+  `user_id` bigint(20) DEFAULT NULL,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `expires_at` datetime DEFAULT NULL,
+  `last_used_at` datetime DEFAULT NULL,
+  `revoked_at` datetime DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uq_auth_api_key` (`api_key`),
+  KEY `idx_auth_user_id` (`user_id`),
+  CONSTRAINT `fk_auth_user_id`
+  FOREIGN KEY (`user_id`)
+  REFERENCES `user` (`id`)
+  ON DELETE CASCADE ON UPDATE CASCADE
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8;
 
 CREATE TABLE `user_profile` (
@@ -17,7 +35,10 @@ CREATE TABLE `user_profile` (
   `phone` varchar(64) NOT NULL,
   `address` varchar(64) NOT NULL,
   `city` varchar(64) NOT NULL,
-  PRIMARY KEY (`user_id`)
+  `version` int NOT NULL DEFAULT 1,
+  `deleted_at` datetime DEFAULT NULL,
+  PRIMARY KEY (`user_id`),
+  KEY `idx_user_profile_deleted_at` (`deleted_at`),
   CONSTRAINT `fk_user_profile_user_id` 
   FOREIGN KEY (`user_id`) 
   REFERENCES `user` (`id`) 
@@ -27,9 +48,92 @@ CREATE TABLE `user_profile` (
 CREATE TABLE `user_data` (
   `user_id` bigint(20) NOT NULL,
   `school` varchar(32) NOT NULL,
-  PRIMARY KEY (`user_id`)
+  `version` int NOT NULL DEFAULT 1,
+  PRIMARY KEY (`user_id`),
   CONSTRAINT `fk_user_data_user_id` 
   FOREIGN KEY (`user_id`) 
   REFERENCES `user` (`id`) 
   ON DELETE CASCADE ON UPDATE CASCADE
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE `audit_log` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `action` varchar(16) NOT NULL,
+  `entity_type` varchar(32) NOT NULL,
+  `entity_id` varchar(64) NOT NULL,
+  `actor_key_id` bigint(20) DEFAULT NULL,
+  `actor_user_id` bigint(20) DEFAULT NULL,
+  `auth_method` varchar(16) NOT NULL DEFAULT '',
+  `request_id` varchar(128) NOT NULL DEFAULT '',
+  `before_data` json DEFAULT NULL,
+  `after_data` json DEFAULT NULL,
+  `changes` json DEFAULT NULL,
+  `created_at` datetime(6) NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_audit_log_entity` (`entity_type`, `entity_id`, `created_at`),
+  KEY `idx_audit_log_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE `idempotency_key` (
+  `scope` varchar(64) NOT NULL,
+  `idempotency_key` varchar(255) NOT NULL,
+  `fingerprint` char(64) NOT NULL,
+  `status_code` int DEFAULT NULL,
+  `content_type` varchar(255) DEFAULT NULL,
+  `response_body` mediumblob,
+  `created_at` datetime NOT NULL,
+  `expires_at` datetime NOT NULL,
+  PRIMARY KEY (`scope`, `idempotency_key`),
+  KEY `idx_idempotency_key_expires_at` (`expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE `webhook` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `url` varchar(2048) NOT NULL,
+  `secret` varchar(64) NOT NULL,
+  `event_types` varchar(1024) NOT NULL,
+  `active` tinyint(1) NOT NULL DEFAULT 1,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE `webhook_delivery` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `webhook_id` bigint(20) NOT NULL,
+  `event_id` varchar(64) NOT NULL,
+  `event_type` varchar(32) NOT NULL,
+  `payload` json NOT NULL,
+  `status` varchar(16) NOT NULL,
+  `attempts` int NOT NULL DEFAULT 0,
+  `next_attempt_at` datetime(6) NOT NULL,
+  `last_status_code` int DEFAULT NULL,
+  `last_error` varchar(1024) NOT NULL DEFAULT '',
+  `created_at` datetime(6) NOT NULL,
+  `delivered_at` datetime(6) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_webhook_delivery_due` (`status`, `next_attempt_at`),
+  KEY `idx_webhook_delivery_webhook_id` (`webhook_id`, `id`),
+  CONSTRAINT `fk_webhook_delivery_webhook_id`
+  FOREIGN KEY (`webhook_id`)
+  REFERENCES `webhook` (`id`)
+  ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE `outbox` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `aggregate_type` varchar(32) NOT NULL,
+  `aggregate_id` varchar(64) NOT NULL,
+  `action` varchar(16) NOT NULL,
+  `payload` json DEFAULT NULL,
+  `request_id` varchar(128) NOT NULL DEFAULT '',
+  `created_at` datetime(6) NOT NULL,
+  `attempts` int NOT NULL DEFAULT 0,
+  `next_attempt_at` datetime(6) NOT NULL,
+  `last_error` varchar(1024) NOT NULL DEFAULT '',
+  `published_to` varchar(255) NOT NULL DEFAULT '',
+  `published_at` datetime(6) DEFAULT NULL,
+  `dead_at` datetime(6) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_outbox_published_at` (`published_at`, `id`),
+  KEY `idx_outbox_aggregate` (`aggregate_type`, `aggregate_id`, `id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
@@ -0,0 +1,148 @@
+package mysql_audit
+
+import (
+	"awesome-clean-arch/internal/audit"
+	"awesome-clean-arch/pkg/client/mysql"
+	"awesome-clean-arch/pkg/logging"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const maxLimit = 1000
+
+type mysqlRepository struct {
+	client mysql.Client
+	logger *logging.Logger
+}
+
+func formatQuery(q string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(q, "\t", ""), "\n", " ")
+}
+
+func (r *mysqlRepository) Create(ctx context.Context, e audit.Entry) error {
+	q := `INSERT INTO audit_log (action, entity_type, entity_id, actor_key_id, actor_user_id, auth_method, request_id,
+	before_data, after_data, changes, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	var changes []byte
+	if e.Changes != nil {
+		var err error
+		if changes, err = json.Marshal(e.Changes); err != nil {
+			return err
+		}
+	}
+
+	_, err := r.client.ExecContext(ctx, q, e.Action, e.EntityType, e.EntityID, e.ActorKeyID, e.ActorUserID,
+		e.AuthMethod, e.RequestID, nullJSON(e.Before), nullJSON(e.After), nullJSON(changes), e.CreatedAt)
+	if err != nil {
+		r.logger.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *mysqlRepository) Find(ctx context.Context, f audit.Filter) ([]audit.Entry, error) {
+	var where []string
+	var args []interface{}
+	if f.EntityType != "" {
+		where, args = append(where, "entity_type = ?"), append(args, f.EntityType)
+	}
+	if f.EntityID != "" {
+		where, args = append(where, "entity_id = ?"), append(args, f.EntityID)
+	}
+	if f.Action != "" {
+		where, args = append(where, "action = ?"), append(args, f.Action)
+	}
+	if !f.From.IsZero() {
+		where, args = append(where, "created_at >= ?"), append(args, f.From)
+	}
+	if !f.To.IsZero() {
+		where, args = append(where, "created_at < ?"), append(args, f.To)
+	}
+
+	limit := f.Limit
+	if limit <= 0 || limit > maxLimit {
+		limit = maxLimit
+	}
+
+	q := `SELECT id, action, entity_type, entity_id, actor_key_id, actor_user_id, auth_method, request_id,
+	before_data, after_data, changes, created_at FROM audit_log`
+	if len(where) > 0 {
+		q += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	q += fmt.Sprintf(` ORDER BY id DESC LIMIT %d;`, limit)
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	rows, err := r.client.QueryContext(ctx, q, args...)
+	if err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]audit.Entry, 0)
+
+	for rows.Next() {
+		var e audit.Entry
+		var keyID, userID sql.NullInt64
+		var before, after, changes []byte
+
+		err = rows.Scan(&e.ID, &e.Action, &e.EntityType, &e.EntityID, &keyID, &userID, &e.AuthMethod, &e.RequestID,
+			&before, &after, &changes, &e.CreatedAt)
+		if err != nil {
+			r.logger.Error(err)
+			return nil, err
+		}
+
+		e.ActorKeyID, e.ActorUserID = intPtr(keyID), intPtr(userID)
+		if len(before) > 0 {
+			e.Before = before
+		}
+		if len(after) > 0 {
+			e.After = after
+		}
+		if len(changes) > 0 {
+			if err = json.Unmarshal(changes, &e.Changes); err != nil {
+				r.logger.Error(err)
+				return nil, err
+			}
+		}
+
+		entries = append(entries, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func nullJSON(b []byte) interface{} {
+	if len(b) == 0 {
+		return nil
+	}
+	return string(b)
+}
+
+func intPtr(n sql.NullInt64) *int {
+	if !n.Valid {
+		return nil
+	}
+	v := int(n.Int64)
+	return &v
+}
+
+func NewMySQLRepository(client mysql.Client, logger *logging.Logger) audit.Repository {
+	return &mysqlRepository{
+		client: client,
+		logger: logger,
+	}
+}
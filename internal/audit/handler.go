@@ -0,0 +1,99 @@
+package audit
+
+import (
+	"awesome-clean-arch/internal/auth"
+	"awesome-clean-arch/internal/handlers"
+	"awesome-clean-arch/pkg/logging"
+	"encoding/json"
+	"fmt"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const auditURL = "/audit"
+
+var _ handlers.Handler = &handler{}
+
+type handler struct {
+	logger     *logging.Logger
+	repository Repository
+}
+
+func NewHandler(logger *logging.Logger, repository Repository) handlers.Handler {
+	return &handler{
+		logger:     logger,
+		repository: repository,
+	}
+}
+
+func (h *handler) Middleware() []handlers.Middleware {
+	return nil
+}
+
+func (h *handler) Register(router handlers.Router) {
+	router.GET(auditURL, h.GetAuditLog, auth.RequireScope(auth.ScopeAuditRead))
+}
+
+func (h *handler) Docs() []handlers.Doc {
+	return []handlers.Doc{
+		{Method: http.MethodGet, Path: auditURL, Summary: "List audit log entries, newest first", Tags: []string{"audit"},
+			Scope: auth.ScopeAuditRead, Response: []Entry{}, Query: []handlers.Param{
+				{Name: "entity_type", Description: "user, profile, user_data or auth"},
+				{Name: "entity_id"},
+				{Name: "action", Description: "create, update, delete, revoke or restore"},
+				{Name: "from", Description: "RFC 3339 time, inclusive"},
+				{Name: "to", Description: "RFC 3339 time, exclusive"},
+				{Name: "limit", Description: "At most 1000"},
+			}},
+	}
+}
+
+// GetAuditLog lists entries newest first. Supported query parameters are
+// entity_type, entity_id, action, from and to (RFC 3339) and limit.
+func (h *handler) GetAuditLog(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	filter, err := parseFilter(r)
+	if err != nil {
+		handlers.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	entries, err := h.repository.Find(r.Context(), filter)
+	if err != nil {
+		handlers.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(entries)
+}
+
+func parseFilter(r *http.Request) (Filter, error) {
+	query := r.URL.Query()
+	f := Filter{
+		EntityType: query.Get("entity_type"),
+		EntityID:   query.Get("entity_id"),
+		Action:     query.Get("action"),
+	}
+
+	var err error
+	if v := query.Get("from"); v != "" {
+		if f.From, err = time.Parse(time.RFC3339, v); err != nil {
+			return Filter{}, fmt.Errorf("from: %w", err)
+		}
+	}
+	if v := query.Get("to"); v != "" {
+		if f.To, err = time.Parse(time.RFC3339, v); err != nil {
+			return Filter{}, fmt.Errorf("to: %w", err)
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		if f.Limit, err = strconv.Atoi(v); err != nil || f.Limit <= 0 {
+			return Filter{}, fmt.Errorf("limit: must be a positive integer")
+		}
+	}
+	return f, nil
+}
@@ -0,0 +1,53 @@
+package audit
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionRevoke  = "revoke"
+	ActionRestore = "restore"
+)
+
+const (
+	EntityUser     = "user"
+	EntityProfile  = "profile"
+	EntityUserData = "user_data"
+	EntityAuth     = "auth"
+)
+
+type Entry struct {
+	ID         int64  `json:"id"`
+	Action     string `json:"action"`
+	EntityType string `json:"entity_type"`
+	EntityID   string `json:"entity_id"`
+	// ActorKeyID and ActorUserID identify the API key and its owner that made
+	// the change, both are nil for changes made outside a request.
+	ActorKeyID  *int              `json:"actor_key_id,omitempty"`
+	ActorUserID *int              `json:"actor_user_id,omitempty"`
+	AuthMethod  string            `json:"auth_method,omitempty"`
+	RequestID   string            `json:"request_id,omitempty"`
+	Before      json.RawMessage   `json:"before,omitempty"`
+	After       json.RawMessage   `json:"after,omitempty"`
+	Changes     map[string]Change `json:"changes,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+type Change struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// Filter narrows Find results, zero values match everything.
+type Filter struct {
+	EntityType string
+	EntityID   string
+	Action     string
+	From       time.Time
+	To         time.Time
+	Limit      int
+}
@@ -0,0 +1,98 @@
+package audit
+
+import (
+	"awesome-clean-arch/internal/auth"
+	"awesome-clean-arch/internal/handlers"
+	"awesome-clean-arch/pkg/logging"
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+)
+
+// Recorder turns successful writes into audit entries. Failing to store an
+// entry is logged and does not undo the write it describes.
+type Recorder struct {
+	repository Repository
+	logger     *logging.Logger
+}
+
+func NewRecorder(repository Repository, logger *logging.Logger) *Recorder {
+	return &Recorder{
+		repository: repository,
+		logger:     logger,
+	}
+}
+
+// Record stores the change of one entity. before is nil for creations and
+// after is nil for deletions.
+func (r *Recorder) Record(ctx context.Context, action, entityType, entityID string, before, after interface{}) {
+	entry := Entry{
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		RequestID:  handlers.RequestIDFromContext(ctx),
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	if a, ok := auth.FromContext(ctx); ok {
+		id := a.ID
+		entry.ActorKeyID = &id
+		entry.ActorUserID = a.UserID
+		entry.AuthMethod = auth.MethodFromContext(ctx)
+	}
+
+	var err error
+	if entry.Before, err = marshal(before); err != nil {
+		r.logger.Errorf("audit: %s", err)
+	}
+	if entry.After, err = marshal(after); err != nil {
+		r.logger.Errorf("audit: %s", err)
+	}
+	entry.Changes = Diff(entry.Before, entry.After)
+
+	// The write is committed, a client that went away must not leave it
+	// unaudited.
+	if err = r.repository.Create(context.WithoutCancel(ctx), entry); err != nil {
+		r.logger.Errorf("audit: failed to record %s of %s %s: %s", action, entityType, entityID, err)
+	}
+}
+
+func marshal(v interface{}) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
+
+// Diff compares the top level fields of two JSON objects. A missing side is
+// treated as an empty object.
+func Diff(before, after json.RawMessage) map[string]Change {
+	var from, to map[string]interface{}
+	if len(before) > 0 {
+		if err := json.Unmarshal(before, &from); err != nil {
+			return nil
+		}
+	}
+	if len(after) > 0 {
+		if err := json.Unmarshal(after, &to); err != nil {
+			return nil
+		}
+	}
+
+	changes := make(map[string]Change)
+	for k, v := range from {
+		if w, ok := to[k]; !ok || !reflect.DeepEqual(v, w) {
+			changes[k] = Change{From: v, To: to[k]}
+		}
+	}
+	for k, w := range to {
+		if _, ok := from[k]; !ok {
+			changes[k] = Change{To: w}
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	return changes
+}
@@ -0,0 +1,250 @@
+package audit
+
+import (
+	"awesome-clean-arch/internal/auth"
+	"awesome-clean-arch/internal/profile"
+	"awesome-clean-arch/internal/user"
+	"awesome-clean-arch/internal/user_data"
+	"context"
+	"strconv"
+	"time"
+)
+
+// The repositories below wrap the domain repositories and record every
+// successful write. Reads are passed through unchanged.
+
+type userRepository struct {
+	user.Repository
+	recorder *Recorder
+}
+
+func NewUserRepository(repository user.Repository, recorder *Recorder) user.Repository {
+	return &userRepository{Repository: repository, recorder: recorder}
+}
+
+func (r *userRepository) Create(ctx context.Context, u user.User) (string, error) {
+	id, err := r.Repository.Create(ctx, u)
+	if err != nil {
+		return "", err
+	}
+	u.ID, _ = strconv.Atoi(id)
+	r.recorder.Record(ctx, ActionCreate, EntityUser, id, nil, u)
+	return id, nil
+}
+
+func (r *userRepository) Update(ctx context.Context, u user.User) error {
+	id := strconv.Itoa(u.ID)
+	before, err := r.Repository.FindOne(ctx, id, user.FindOptions{})
+	if err := r.Repository.Update(ctx, u); err != nil {
+		return err
+	}
+	u.Version++
+	r.recorder.Record(ctx, ActionUpdate, EntityUser, id, orNil(before, err), u)
+	return nil
+}
+
+func (r *userRepository) Delete(ctx context.Context, ID string, version int) error {
+	before, err := r.Repository.FindOne(ctx, ID, user.FindOptions{})
+	if err := r.Repository.Delete(ctx, ID, version); err != nil {
+		return err
+	}
+	after, _ := r.Repository.FindOne(ctx, ID, user.FindOptions{IncludeDeleted: true})
+	r.recorder.Record(ctx, ActionDelete, EntityUser, ID, orNil(before, err), after)
+	return nil
+}
+
+func (r *userRepository) Restore(ctx context.Context, ID string) error {
+	before, err := r.Repository.FindOne(ctx, ID, user.FindOptions{IncludeDeleted: true})
+	if err := r.Repository.Restore(ctx, ID); err != nil {
+		return err
+	}
+	after, _ := r.Repository.FindOne(ctx, ID, user.FindOptions{})
+	r.recorder.Record(ctx, ActionRestore, EntityUser, ID, orNil(before, err), after)
+	return nil
+}
+
+type profileRepository struct {
+	profile.Repository
+	recorder *Recorder
+}
+
+func NewProfileRepository(repository profile.Repository, recorder *Recorder) profile.Repository {
+	return &profileRepository{Repository: repository, recorder: recorder}
+}
+
+func (r *profileRepository) Create(ctx context.Context, p profile.Profile) (string, error) {
+	id, err := r.Repository.Create(ctx, p)
+	if err != nil {
+		return "", err
+	}
+	p.ID = id
+	r.recorder.Record(ctx, ActionCreate, EntityProfile, id, nil, p)
+	return id, nil
+}
+
+func (r *profileRepository) CreateBatch(ctx context.Context, profiles []profile.Profile, dryRun bool) ([]string, error) {
+	ids, err := r.Repository.CreateBatch(ctx, profiles, dryRun)
+	if err != nil || dryRun {
+		return ids, err
+	}
+	for i, id := range ids {
+		if id == "" {
+			continue
+		}
+		p := profiles[i]
+		p.ID = id
+		r.recorder.Record(ctx, ActionCreate, EntityProfile, id, nil, p)
+	}
+	return ids, nil
+}
+
+// Profiles are looked up by username, so the previous state is only known
+// when the update carries one.
+func (r *profileRepository) Update(ctx context.Context, p profile.Profile) error {
+	var before interface{}
+	if p.Username != "" {
+		b, err := r.Repository.FindOne(ctx, p.Username, profile.FindOptions{})
+		before = orNil(b, err)
+	}
+	if err := r.Repository.Update(ctx, p); err != nil {
+		return err
+	}
+	p.Version++
+	r.recorder.Record(ctx, ActionUpdate, EntityProfile, p.ID, before, p)
+	return nil
+}
+
+func (r *profileRepository) Delete(ctx context.Context, userID string, version int) error {
+	before := r.find(ctx, userID)
+	if err := r.Repository.Delete(ctx, userID, version); err != nil {
+		return err
+	}
+	r.recorder.Record(ctx, ActionDelete, EntityProfile, userID, before, r.find(ctx, userID))
+	return nil
+}
+
+func (r *profileRepository) Restore(ctx context.Context, userID string) error {
+	before := r.find(ctx, userID)
+	if err := r.Repository.Restore(ctx, userID); err != nil {
+		return err
+	}
+	r.recorder.Record(ctx, ActionRestore, EntityProfile, userID, before, r.find(ctx, userID))
+	return nil
+}
+
+// find returns the profile of userID, deleted or not, or nil when it cannot
+// be looked up.
+func (r *profileRepository) find(ctx context.Context, userID string) interface{} {
+	found, err := r.Repository.FindByUserIDs(ctx, []string{userID}, profile.FindOptions{IncludeDeleted: true})
+	if err != nil || len(found) == 0 {
+		return nil
+	}
+	return found[0]
+}
+
+type userDataRepository struct {
+	user_data.Repository
+	recorder *Recorder
+}
+
+func NewUserDataRepository(repository user_data.Repository, recorder *Recorder) user_data.Repository {
+	return &userDataRepository{Repository: repository, recorder: recorder}
+}
+
+func (r *userDataRepository) Create(ctx context.Context, ud user_data.UserData) (string, error) {
+	id, err := r.Repository.Create(ctx, ud)
+	if err != nil {
+		return "", err
+	}
+	ud.ID, _ = strconv.Atoi(id)
+	r.recorder.Record(ctx, ActionCreate, EntityUserData, id, nil, ud)
+	return id, nil
+}
+
+func (r *userDataRepository) Update(ctx context.Context, ud user_data.UserData) error {
+	id := strconv.Itoa(ud.ID)
+	before, err := r.Repository.FindOne(ctx, id)
+	if err := r.Repository.Update(ctx, ud); err != nil {
+		return err
+	}
+	ud.Version++
+	r.recorder.Record(ctx, ActionUpdate, EntityUserData, id, orNil(before, err), ud)
+	return nil
+}
+
+func (r *userDataRepository) Delete(ctx context.Context, userID string) error {
+	before, err := r.Repository.FindOne(ctx, userID)
+	if err := r.Repository.Delete(ctx, userID); err != nil {
+		return err
+	}
+	r.recorder.Record(ctx, ActionDelete, EntityUserData, userID, orNil(before, err), nil)
+	return nil
+}
+
+// authRepository records key changes with the key and signing secret masked.
+// Last-used updates are bookkeeping and are not audited.
+type authRepository struct {
+	auth.Repository
+	recorder *Recorder
+}
+
+func NewAuthRepository(repository auth.Repository, recorder *Recorder) auth.Repository {
+	return &authRepository{Repository: repository, recorder: recorder}
+}
+
+func (r *authRepository) Create(ctx context.Context, a auth.Auth) (string, error) {
+	id, err := r.Repository.Create(ctx, a)
+	if err != nil {
+		return "", err
+	}
+	// Reload to pick up the timestamps set by the database.
+	if created, err := r.Repository.FindOne(ctx, id); err == nil {
+		a = created
+	} else {
+		a.ID, _ = strconv.Atoi(id)
+	}
+	r.recorder.Record(ctx, ActionCreate, EntityAuth, id, nil, a.Masked())
+	return id, nil
+}
+
+func (r *authRepository) Update(ctx context.Context, a auth.Auth) error {
+	id := strconv.Itoa(a.ID)
+	before, err := r.Repository.FindOne(ctx, id)
+	if err := r.Repository.Update(ctx, a); err != nil {
+		return err
+	}
+	r.recorder.Record(ctx, ActionUpdate, EntityAuth, id, orNil(before.Masked(), err), a.Masked())
+	return nil
+}
+
+func (r *authRepository) Revoke(ctx context.Context, id string, at time.Time) error {
+	before, err := r.Repository.FindOne(ctx, id)
+	if err := r.Repository.Revoke(ctx, id, at); err != nil {
+		return err
+	}
+	var after interface{}
+	if err == nil {
+		revoked := before
+		revoked.RevokedAt = &at
+		after = revoked.Masked()
+	}
+	r.recorder.Record(ctx, ActionRevoke, EntityAuth, id, orNil(before.Masked(), err), after)
+	return nil
+}
+
+func (r *authRepository) Delete(ctx context.Context, id string) error {
+	before, err := r.Repository.FindOne(ctx, id)
+	if err := r.Repository.Delete(ctx, id); err != nil {
+		return err
+	}
+	r.recorder.Record(ctx, ActionDelete, EntityAuth, id, orNil(before.Masked(), err), nil)
+	return nil
+}
+
+// orNil drops the previous state when it could not be loaded.
+func orNil(v interface{}, err error) interface{} {
+	if err != nil {
+		return nil
+	}
+	return v
+}
@@ -0,0 +1,8 @@
+package audit
+
+import "context"
+
+type Repository interface {
+	Create(ctx context.Context, entry Entry) error
+	Find(ctx context.Context, filter Filter) ([]Entry, error)
+}
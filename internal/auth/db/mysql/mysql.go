@@ -5,11 +5,15 @@ import (
 	"awesome-clean-arch/pkg/client/mysql"
 	"awesome-clean-arch/pkg/logging"
 	"context"
+	"database/sql"
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 )
 
+const authColumns = `id, api_key, scopes, signing_secret, user_id, rate_limit, rate_burst, created_at, expires_at, last_used_at, revoked_at`
+
 type mysqlRepository struct {
 	client mysql.Client
 	logger *logging.Logger
@@ -20,11 +24,12 @@ func formatQuery(q string) string {
 }
 
 func (r *mysqlRepository) Create(ctx context.Context, auth auth.Auth) (string, error) {
-	q := `INSERT INTO auth (api_key) VALUES (?);`
+	q := `INSERT INTO auth (api_key, scopes, signing_secret, user_id, rate_limit, rate_burst, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?);`
 
 	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
 
-	res, err := r.client.ExecContext(ctx, q, auth.APIKey)
+	res, err := r.client.ExecContext(ctx, q, auth.APIKey, strings.Join(auth.Scopes, ","), nullString(auth.SigningSecret), auth.UserID,
+		nullFloat(auth.RateLimit), nullInt(auth.RateBurst), auth.ExpiresAt)
 	if err != nil {
 		r.logger.Error(err)
 		return "", err
@@ -40,11 +45,33 @@ func (r *mysqlRepository) Create(ctx context.Context, auth auth.Auth) (string, e
 }
 
 func (r *mysqlRepository) FindAll(ctx context.Context) (u []auth.Auth, err error) {
-	q := `SELECT id, api_key FROM auth;`
+	q := `SELECT ` + authColumns + ` FROM auth;`
+
+	return r.findMany(ctx, q)
+}
+
+func (r *mysqlRepository) FindByUserID(ctx context.Context, userID string) ([]auth.Auth, error) {
+	q := `SELECT ` + authColumns + ` FROM auth WHERE user_id = ?;`
+
+	return r.findMany(ctx, q, userID)
+}
+
+// FindByUserIDs returns the keys owned by the users among userIDs.
+func (r *mysqlRepository) FindByUserIDs(ctx context.Context, userIDs []string) ([]auth.Auth, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+
+	in, args := mysql.In(userIDs)
+	q := `SELECT ` + authColumns + ` FROM auth WHERE user_id IN (` + in + `);`
+
+	return r.findMany(ctx, q, args...)
+}
 
+func (r *mysqlRepository) findMany(ctx context.Context, q string, args ...interface{}) ([]auth.Auth, error) {
 	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
 
-	rows, err := r.client.QueryContext(ctx, q)
+	rows, err := r.client.QueryContext(ctx, q, args...)
 	if err != nil {
 		r.logger.Error(err)
 		return nil, err
@@ -54,9 +81,7 @@ func (r *mysqlRepository) FindAll(ctx context.Context) (u []auth.Auth, err error
 	keys := make([]auth.Auth, 0)
 
 	for rows.Next() {
-		var a auth.Auth
-
-		err = rows.Scan(&a.ID, &a.APIKey)
+		a, err := scanAuth(rows)
 		if err != nil {
 			r.logger.Error(err)
 			return nil, err
@@ -74,13 +99,11 @@ func (r *mysqlRepository) FindAll(ctx context.Context) (u []auth.Auth, err error
 }
 
 func (r *mysqlRepository) FindOne(ctx context.Context, ID string) (auth.Auth, error) {
-	q := `SELECT id, api_key FROM auth WHERE id = ?;`
+	q := `SELECT ` + authColumns + ` FROM auth WHERE id = ?;`
 
 	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
 
-	var a auth.Auth
-
-	err := r.client.QueryRowContext(ctx, q, ID).Scan(&a.ID, &a.APIKey)
+	a, err := scanAuth(r.client.QueryRowContext(ctx, q, ID))
 	if err != nil {
 		r.logger.Error(err)
 		return auth.Auth{}, err
@@ -89,12 +112,43 @@ func (r *mysqlRepository) FindOne(ctx context.Context, ID string) (auth.Auth, er
 	return a, nil
 }
 
+func (r *mysqlRepository) FindByAPIKey(ctx context.Context, apiKey string) (auth.Auth, error) {
+	q := `SELECT ` + authColumns + ` FROM auth WHERE api_key = ?;`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	a, err := scanAuth(r.client.QueryRowContext(ctx, q, apiKey))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			r.logger.Error(err)
+		}
+		return auth.Auth{}, err
+	}
+
+	return a, nil
+}
+
 func (r *mysqlRepository) Update(ctx context.Context, auth auth.Auth) error {
-	q := `UPDATE auth SET api_key = ? WHERE id = ?;`
+	q := `UPDATE auth SET api_key = ?, scopes = ?, signing_secret = ?, user_id = ?, rate_limit = ?, rate_burst = ?, expires_at = ? WHERE id = ?;`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	_, err := r.client.ExecContext(ctx, q, auth.APIKey, strings.Join(auth.Scopes, ","), nullString(auth.SigningSecret), auth.UserID,
+		nullFloat(auth.RateLimit), nullInt(auth.RateBurst), auth.ExpiresAt, auth.ID)
+	if err != nil {
+		r.logger.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *mysqlRepository) Revoke(ctx context.Context, ID string, at time.Time) error {
+	q := `UPDATE auth SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL;`
 
 	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
 
-	_, err := r.client.ExecContext(ctx, q, auth.APIKey, auth.ID)
+	_, err := r.client.ExecContext(ctx, q, at, ID)
 	if err != nil {
 		r.logger.Error(err)
 		return err
@@ -103,6 +157,35 @@ func (r *mysqlRepository) Update(ctx context.Context, auth auth.Auth) error {
 	return nil
 }
 
+func (r *mysqlRepository) UpdateLastUsed(ctx context.Context, usage map[int]time.Time) error {
+	q := `UPDATE auth SET last_used_at = ? WHERE id = ? AND (last_used_at IS NULL OR last_used_at < ?);`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	tx, err := r.client.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error(err)
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, q)
+	if err != nil {
+		r.logger.Error(err)
+		return err
+	}
+	defer stmt.Close()
+
+	for id, at := range usage {
+		if _, err = stmt.ExecContext(ctx, at, id, at); err != nil {
+			r.logger.Error(err)
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
 func (r *mysqlRepository) Delete(ctx context.Context, ID string) error {
 	q := `DELETE FROM auth WHERE id = ?;`
 
@@ -117,6 +200,62 @@ func (r *mysqlRepository) Delete(ctx context.Context, ID string) error {
 	return nil
 }
 
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAuth(row scanner) (auth.Auth, error) {
+	var a auth.Auth
+	var scopes string
+	var signingSecret sql.NullString
+	var userID sql.NullInt64
+	var rateLimit sql.NullFloat64
+	var rateBurst sql.NullInt64
+	var expiresAt, lastUsedAt, revokedAt sql.NullTime
+
+	err := row.Scan(&a.ID, &a.APIKey, &scopes, &signingSecret, &userID, &rateLimit, &rateBurst,
+		&a.CreatedAt, &expiresAt, &lastUsedAt, &revokedAt)
+	if err != nil {
+		return auth.Auth{}, err
+	}
+
+	a.Scopes = make([]string, 0)
+	if scopes != "" {
+		a.Scopes = strings.Split(scopes, ",")
+	}
+	a.SigningSecret = signingSecret.String
+	if userID.Valid {
+		id := int(userID.Int64)
+		a.UserID = &id
+	}
+	a.RateLimit = rateLimit.Float64
+	a.RateBurst = int(rateBurst.Int64)
+	a.ExpiresAt = timePtr(expiresAt)
+	a.LastUsedAt = timePtr(lastUsedAt)
+	a.RevokedAt = timePtr(revokedAt)
+
+	return a, nil
+}
+
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func nullFloat(f float64) sql.NullFloat64 {
+	return sql.NullFloat64{Float64: f, Valid: f > 0}
+}
+
+func nullInt(i int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(i), Valid: i > 0}
+}
+
 func NewMySQLRepository(client mysql.Client, logger *logging.Logger) auth.Repository {
 	return &mysqlRepository{
 		client: client,
@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"awesome-clean-arch/internal/grpcapi"
+	apiv1 "awesome-clean-arch/pkg/api/v1"
+	"awesome-clean-arch/pkg/logging"
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"strconv"
+	"time"
+)
+
+var _ grpcapi.Service = &grpcService{}
+
+type grpcService struct {
+	apiv1.UnimplementedAuthServiceServer
+	logger     *logging.Logger
+	repository Repository
+}
+
+func NewGRPCService(logger *logging.Logger, repository Repository) grpcapi.Service {
+	return &grpcService{
+		logger:     logger,
+		repository: repository,
+	}
+}
+
+func (s *grpcService) Register(server *grpc.Server) {
+	apiv1.RegisterAuthServiceServer(server, s)
+}
+
+func (s *grpcService) Scopes() map[string]string {
+	desc := apiv1.AuthService_ServiceDesc
+	return map[string]string{
+		grpcapi.FullMethod(desc, "CreateKey"):    ScopeAuthAdmin,
+		grpcapi.FullMethod(desc, "ListKeys"):     ScopeAuthAdmin,
+		grpcapi.FullMethod(desc, "GetKey"):       ScopeAuthAdmin,
+		grpcapi.FullMethod(desc, "ListUserKeys"): "",
+		grpcapi.FullMethod(desc, "UpdateKey"):    ScopeAuthAdmin,
+		grpcapi.FullMethod(desc, "RevokeKey"):    ScopeAuthAdmin,
+		grpcapi.FullMethod(desc, "DeleteKey"):    ScopeAuthAdmin,
+	}
+}
+
+func (s *grpcService) CreateKey(ctx context.Context, req *apiv1.CreateKeyRequest) (*apiv1.CreateKeyResponse, error) {
+	a := authFromProto(req.GetKey())
+	if err := validateScopes(a.Scopes); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	a.ID, a.CreatedAt, a.LastUsedAt, a.RevokedAt, a.SigningSecret = 0, time.Time{}, nil, nil, ""
+
+	var err error
+	if a.APIKey == "" {
+		if a.APIKey, err = generateAPIKey(); err != nil {
+			return nil, grpcapi.Error(err, "")
+		}
+	}
+	if req.GetGenerateSigningSecret() {
+		if a.SigningSecret, err = generateSigningSecret(); err != nil {
+			return nil, grpcapi.Error(err, "")
+		}
+	}
+
+	id, err := s.repository.Create(ctx, a)
+	if err != nil {
+		return nil, grpcapi.Error(err, "")
+	}
+	return &apiv1.CreateKeyResponse{Id: id, ApiKey: a.APIKey, SigningSecret: a.SigningSecret}, nil
+}
+
+func (s *grpcService) ListKeys(ctx context.Context, req *apiv1.ListKeysRequest) (*apiv1.ListKeysResponse, error) {
+	all, err := s.repository.FindAll(ctx)
+	if err != nil {
+		return nil, grpcapi.Error(err, "")
+	}
+	for i := range all {
+		all[i] = all[i].WithoutSecret()
+	}
+	return &apiv1.ListKeysResponse{Keys: authsToProto(all)}, nil
+}
+
+func (s *grpcService) GetKey(ctx context.Context, req *apiv1.GetKeyRequest) (*apiv1.Key, error) {
+	a, err := s.repository.FindOne(ctx, req.GetId())
+	if err != nil {
+		return nil, grpcapi.Error(err, "API key not found")
+	}
+	return authToProto(a.WithoutSecret()), nil
+}
+
+func (s *grpcService) ListUserKeys(ctx context.Context, req *apiv1.ListUserKeysRequest) (*apiv1.ListKeysResponse, error) {
+	caller, _ := FromContext(ctx)
+	if !caller.HasScope(ScopeAuthAdmin) && (caller.UserID == nil || strconv.Itoa(*caller.UserID) != req.GetUserId()) {
+		return nil, status.Error(codes.PermissionDenied, "not the owner of these keys")
+	}
+
+	keys, err := s.repository.FindByUserID(ctx, req.GetUserId())
+	if err != nil {
+		return nil, grpcapi.Error(err, "")
+	}
+	for i := range keys {
+		keys[i] = keys[i].Masked()
+	}
+	return &apiv1.ListKeysResponse{Keys: authsToProto(keys)}, nil
+}
+
+// UpdateKey changes the scopes, owner, rate limit and expiry of a key.
+func (s *grpcService) UpdateKey(ctx context.Context, req *apiv1.UpdateKeyRequest) (*apiv1.Key, error) {
+	id := strconv.FormatInt(req.GetKey().GetId(), 10)
+	current, err := s.repository.FindOne(ctx, id)
+	if err != nil {
+		return nil, grpcapi.Error(err, "API key not found")
+	}
+
+	update := authFromProto(req.GetKey())
+	if err = validateScopes(update.Scopes); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	a := current
+	a.Scopes, a.UserID, a.RateLimit, a.RateBurst, a.ExpiresAt = update.Scopes, update.UserID, update.RateLimit, update.RateBurst, update.ExpiresAt
+	if err = s.repository.Update(ctx, a); err != nil {
+		return nil, grpcapi.Error(err, "API key not found")
+	}
+	return authToProto(a.WithoutSecret()), nil
+}
+
+func (s *grpcService) RevokeKey(ctx context.Context, req *apiv1.RevokeKeyRequest) (*emptypb.Empty, error) {
+	if _, err := s.repository.FindOne(ctx, req.GetId()); err != nil {
+		return nil, grpcapi.Error(err, "API key not found")
+	}
+	if err := s.repository.Revoke(ctx, req.GetId(), time.Now()); err != nil {
+		return nil, grpcapi.Error(err, "API key not found")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *grpcService) DeleteKey(ctx context.Context, req *apiv1.DeleteKeyRequest) (*emptypb.Empty, error) {
+	if caller, ok := FromContext(ctx); ok && strconv.Itoa(caller.ID) == req.GetId() {
+		return nil, status.Error(codes.FailedPrecondition, "an API key cannot delete itself")
+	}
+	if err := s.repository.Delete(ctx, req.GetId()); err != nil {
+		return nil, grpcapi.Error(err, "API key not found")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func authToProto(a Auth) *apiv1.Key {
+	k := &apiv1.Key{
+		Id:            int64(a.ID),
+		ApiKey:        a.APIKey,
+		Scopes:        a.Scopes,
+		SigningSecret: a.SigningSecret,
+		RateLimit:     a.RateLimit,
+		RateBurst:     int32(a.RateBurst),
+		CreatedAt:     timestamppb.New(a.CreatedAt),
+		ExpiresAt:     grpcapi.Timestamp(a.ExpiresAt),
+		LastUsedAt:    grpcapi.Timestamp(a.LastUsedAt),
+		RevokedAt:     grpcapi.Timestamp(a.RevokedAt),
+	}
+	if a.UserID != nil {
+		userID := int64(*a.UserID)
+		k.UserId = &userID
+	}
+	return k
+}
+
+func authsToProto(all []Auth) []*apiv1.Key {
+	keys := make([]*apiv1.Key, len(all))
+	for i, a := range all {
+		keys[i] = authToProto(a)
+	}
+	return keys
+}
+
+func authFromProto(k *apiv1.Key) Auth {
+	a := Auth{
+		ID:            int(k.GetId()),
+		APIKey:        k.GetApiKey(),
+		Scopes:        k.GetScopes(),
+		SigningSecret: k.GetSigningSecret(),
+		RateLimit:     k.GetRateLimit(),
+		RateBurst:     int(k.GetRateBurst()),
+		ExpiresAt:     grpcapi.Time(k.GetExpiresAt()),
+	}
+	if k.UserId != nil {
+		userID := int(k.GetUserId())
+		a.UserID = &userID
+	}
+	return a
+}
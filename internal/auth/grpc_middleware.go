@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"awesome-clean-arch/internal/grpcapi"
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"strings"
+)
+
+// APIKeyMetadata is the metadata key carrying the API key of gRPC calls.
+var APIKeyMetadata = strings.ToLower(APIKeyHeader)
+
+// UnaryInterceptor authenticates gRPC calls like Middleware does HTTP
+// requests, from the x-api-key or authorization metadata, and enforces the
+// scope required by the method in scopes. Request signing is HTTP only.
+func (au *Authenticator) UnaryInterceptor(scopes map[string]string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := au.authorize(ctx, info.FullMethod, scopes)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (au *Authenticator) StreamInterceptor(scopes map[string]string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := au.authorize(ss.Context(), info.FullMethod, scopes)
+		if err != nil {
+			return err
+		}
+		return handler(srv, grpcapi.WrapStream(ss, ctx))
+	}
+}
+
+func (au *Authenticator) authorize(ctx context.Context, method string, scopes map[string]string) (context.Context, error) {
+	ctx, err := au.authenticateMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	scope, ok := scopes[method]
+	if !ok {
+		return ctx, nil
+	}
+	a, ok := FromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "API key required")
+	}
+	if scope != "" && !a.HasScope(scope) {
+		return nil, status.Error(codes.PermissionDenied, "missing scope "+scope)
+	}
+	return ctx, nil
+}
+
+// authenticateMetadata stores the principal of the call in ctx. Calls
+// without credentials pass through anonymously.
+func (au *Authenticator) authenticateMetadata(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	var key string
+	if values := md.Get(APIKeyMetadata); len(values) > 0 {
+		key = values[0]
+	}
+	if values := md.Get("authorization"); key == "" && len(values) > 0 {
+		scheme, credentials, _ := strings.Cut(values[0], " ")
+		credentials = strings.TrimSpace(credentials)
+		switch {
+		case strings.EqualFold(scheme, "Bearer") && au.tokens != nil:
+			a, err := au.authenticateToken(ctx, credentials)
+			if err != nil {
+				if isCredentialError(err) {
+					return nil, status.Error(codes.Unauthenticated, err.Error())
+				}
+				au.logger.Error(err)
+				return nil, status.Error(codes.Internal, "authentication failed")
+			}
+			return withPrincipal(ctx, a, MethodBearer), nil
+		case strings.EqualFold(scheme, "ApiKey"):
+			key = credentials
+		}
+	}
+	if key == "" {
+		return ctx, nil
+	}
+
+	a, err := au.authenticateKey(ctx, key)
+	if err != nil {
+		if isCredentialError(err) {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		au.logger.Error(err)
+		return nil, status.Error(codes.Internal, "authentication failed")
+	}
+	return withPrincipal(ctx, a, MethodAPIKey), nil
+}
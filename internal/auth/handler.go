@@ -3,19 +3,31 @@ package auth
 import (
 	"awesome-clean-arch/internal/handlers"
 	"awesome-clean-arch/pkg/logging"
-	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"github.com/julienschmidt/httprouter"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 const (
-	authsURL = "/auth"
-	authURL  = "/auth/:id"
+	authsURL      = "/auth"
+	authURL       = "/auth/:id"
+	revokeAuthURL = "/auth/:id/revoke"
+	userAuthsURL  = "/user/:id/keys"
 )
 
 var _ handlers.Handler = &handler{}
 
+type CreateAuthRequest struct {
+	Auth
+	GenerateSigningSecret bool `json:"generate_signing_secret"`
+}
+
 type handler struct {
 	logger     *logging.Logger
 	repository Repository
@@ -28,18 +40,52 @@ func NewHandler(logger *logging.Logger, repository Repository) handlers.Handler
 	}
 }
 
-func (h *handler) Register(router *httprouter.Router) {
-	router.GET(authsURL, h.GetAuthsList)
-	router.GET(authURL, h.GetAuth)
+func (h *handler) Middleware() []handlers.Middleware {
+	return nil
+}
+
+func (h *handler) Register(router handlers.Router) {
+	admin := router.Group("", RequireScope(ScopeAuthAdmin))
+	admin.GET(authsURL, h.GetAuthsList)
+	admin.GET(authURL, h.GetAuth)
+	// The response carries the key and its signing secret, it must not be kept for replay.
+	admin.With(handlers.NoReplay).POST(authsURL, h.CreateAuth)
+	admin.PUT(authURL, h.UpdateAuth)
+	admin.DELETE(authURL, h.DeleteAuth)
+	admin.POST(revokeAuthURL, h.RevokeAuth)
+
+	router.GET(userAuthsURL, h.GetUserAuthsList)
+}
+
+func (h *handler) Docs() []handlers.Doc {
+	tags := []string{"api keys"}
+	return []handlers.Doc{
+		{Method: http.MethodGet, Path: authsURL, Summary: "List API keys", Tags: tags, Scope: ScopeAuthAdmin, Response: []Auth{}},
+		{Method: http.MethodPost, Path: authsURL, Summary: "Create an API key", Tags: tags, Scope: ScopeAuthAdmin,
+			Description: "The signing secret is only returned here.",
+			Request:     CreateAuthRequest{}, Response: map[string]string{}, Status: http.StatusCreated},
+		{Method: http.MethodGet, Path: authURL, Summary: "Get an API key", Tags: tags, Scope: ScopeAuthAdmin, Response: Auth{}},
+		{Method: http.MethodPut, Path: authURL, Summary: "Update an API key", Tags: tags, Scope: ScopeAuthAdmin,
+			Request: Auth{}, Response: Auth{}},
+		{Method: http.MethodDelete, Path: authURL, Summary: "Delete an API key", Tags: tags, Scope: ScopeAuthAdmin,
+			Status: http.StatusNoContent},
+		{Method: http.MethodPost, Path: revokeAuthURL, Summary: "Revoke an API key", Tags: tags, Scope: ScopeAuthAdmin,
+			Status: http.StatusNoContent},
+		{Method: http.MethodGet, Path: userAuthsURL, Summary: "List the masked keys of a user", Tags: tags,
+			Description: "Available to the owner of the keys and to admins.", Response: []Auth{}},
+	}
 }
 
 func (h *handler) GetAuthsList(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	w.Header().Set("Content-Type", "application/json")
-	all, err := h.repository.FindAll(context.TODO())
+	all, err := h.repository.FindAll(r.Context())
 	if err != nil {
 		w.WriteHeader(400)
 		return
 	}
+	for i := range all {
+		all[i] = all[i].WithoutSecret()
+	}
 
 	allBytes, err := json.Marshal(all)
 	if err != nil {
@@ -53,5 +99,196 @@ func (h *handler) GetAuthsList(w http.ResponseWriter, r *http.Request, params ht
 }
 
 func (h *handler) GetAuth(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
-	w.Write([]byte("Auth ID"))
+	w.Header().Set("Content-Type", "application/json")
+
+	a, err := h.repository.FindOne(r.Context(), params.ByName("id"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			handlers.WriteError(w, http.StatusNotFound, "API key not found")
+			return
+		}
+		handlers.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(a.WithoutSecret())
+}
+
+func (h *handler) CreateAuth(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req CreateAuthRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handlers.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	a := req.Auth
+	if err := validateScopes(a.Scopes); err != nil {
+		handlers.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	a.CreatedAt, a.LastUsedAt, a.RevokedAt = time.Time{}, nil, nil
+
+	if a.APIKey == "" {
+		key, err := generateAPIKey()
+		if err != nil {
+			handlers.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		a.APIKey = key
+	}
+
+	if req.GenerateSigningSecret {
+		secret, err := generateSigningSecret()
+		if err != nil {
+			handlers.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		a.SigningSecret = secret
+	}
+
+	id, err := h.repository.Create(r.Context(), a)
+	if err != nil {
+		handlers.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := map[string]string{"id": id, "api_key": a.APIKey}
+	if a.SigningSecret != "" {
+		response["signing_secret"] = a.SigningSecret
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *handler) UpdateAuth(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	current, err := h.repository.FindOne(r.Context(), params.ByName("id"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			handlers.WriteError(w, http.StatusNotFound, "API key not found")
+			return
+		}
+		handlers.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	a := current
+	if err = json.NewDecoder(r.Body).Decode(&a); err != nil {
+		handlers.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err = validateScopes(a.Scopes); err != nil {
+		handlers.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	a.ID = current.ID
+	// The masked secret read from GET keeps the stored one.
+	if a.SigningSecret == current.WithoutSecret().SigningSecret {
+		a.SigningSecret = current.SigningSecret
+	}
+	a.CreatedAt, a.LastUsedAt, a.RevokedAt = current.CreatedAt, current.LastUsedAt, current.RevokedAt
+
+	if err = h.repository.Update(r.Context(), a); err != nil {
+		handlers.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(a.WithoutSecret())
+}
+
+func (h *handler) DeleteAuth(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	id := params.ByName("id")
+
+	if caller, ok := FromContext(r.Context()); ok && strconv.Itoa(caller.ID) == id {
+		handlers.WriteError(w, http.StatusConflict, "an API key cannot delete itself")
+		return
+	}
+
+	if err := h.repository.Delete(r.Context(), id); err != nil {
+		handlers.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler) RevokeAuth(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	id := params.ByName("id")
+
+	if _, err := h.repository.FindOne(r.Context(), id); err != nil {
+		if err == sql.ErrNoRows {
+			handlers.WriteError(w, http.StatusNotFound, "API key not found")
+			return
+		}
+		handlers.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.repository.Revoke(r.Context(), id, time.Now()); err != nil {
+		handlers.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetUserAuthsList lists the keys owned by a user. Keys are masked; it is
+// available to the owner and to admins.
+func (h *handler) GetUserAuthsList(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	userID := params.ByName("id")
+
+	caller, ok := FromContext(r.Context())
+	if !ok {
+		handlers.WriteError(w, http.StatusUnauthorized, "API key required")
+		return
+	}
+	if !caller.HasScope(ScopeAuthAdmin) && (caller.UserID == nil || strconv.Itoa(*caller.UserID) != userID) {
+		handlers.WriteError(w, http.StatusForbidden, "not the owner of these keys")
+		return
+	}
+
+	keys, err := h.repository.FindByUserID(r.Context(), userID)
+	if err != nil {
+		handlers.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	for i := range keys {
+		keys[i] = keys[i].Masked()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(keys)
+}
+
+func validateScopes(scopes []string) error {
+	for _, s := range scopes {
+		if !IsKnownScope(s) {
+			return fmt.Errorf("unknown scope %q", s)
+		}
+	}
+	return nil
+}
+
+func generateAPIKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func generateSigningSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
 }
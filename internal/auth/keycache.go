@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// keyCacheTTL bounds how long a revoked or deleted key's tokens keep working.
+const keyCacheTTL = 10 * time.Second
+
+type cachedKey struct {
+	auth      Auth
+	found     bool
+	fetchedAt time.Time
+}
+
+// keyCache keeps the keys bearer tokens were issued for, so verifying a token
+// costs at most one lookup per key and keyCacheTTL.
+type keyCache struct {
+	repository Repository
+
+	mu   sync.Mutex
+	keys map[int]cachedKey
+}
+
+func newKeyCache(repository Repository) *keyCache {
+	return &keyCache{
+		repository: repository,
+		keys:       make(map[int]cachedKey),
+	}
+}
+
+// get returns the stored key id, or ErrInvalidAPIKey when it was deleted.
+func (c *keyCache) get(ctx context.Context, id int, now time.Time) (Auth, error) {
+	c.mu.Lock()
+	k, ok := c.keys[id]
+	c.mu.Unlock()
+
+	if !ok || now.Sub(k.fetchedAt) >= keyCacheTTL {
+		a, err := c.repository.FindOne(ctx, strconv.Itoa(id))
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return Auth{}, err
+		}
+		k = cachedKey{auth: a, found: err == nil, fetchedAt: now}
+
+		c.mu.Lock()
+		c.keys[id] = k
+		c.mu.Unlock()
+	}
+
+	if !k.found {
+		return Auth{}, ErrInvalidAPIKey
+	}
+	return k.auth, nil
+}
@@ -0,0 +1,247 @@
+package auth
+
+import (
+	"awesome-clean-arch/internal/handlers"
+	"awesome-clean-arch/pkg/httpsign"
+	"awesome-clean-arch/pkg/logging"
+	"awesome-clean-arch/pkg/ratelimit"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+type ctxKey int
+
+const (
+	authKey ctxKey = iota
+	methodKey
+)
+
+const (
+	MethodAPIKey    = "api_key"
+	MethodBearer    = "bearer"
+	MethodSignature = "signature"
+)
+
+func WithAuth(ctx context.Context, a Auth) context.Context {
+	return context.WithValue(ctx, authKey, a)
+}
+
+func FromContext(ctx context.Context) (Auth, bool) {
+	a, ok := ctx.Value(authKey).(Auth)
+	return a, ok
+}
+
+// MethodFromContext tells how the request was authenticated.
+func MethodFromContext(ctx context.Context) string {
+	m, _ := ctx.Value(methodKey).(string)
+	return m
+}
+
+func UserIDFromContext(ctx context.Context) (int, bool) {
+	a, ok := FromContext(ctx)
+	if !ok || a.UserID == nil {
+		return 0, false
+	}
+	return *a.UserID, true
+}
+
+type Authenticator struct {
+	repository Repository
+	tracker    *UsageTracker
+	tokens     *TokenService
+	signatures *httpsign.Verifier
+	keys       *keyCache
+	logger     *logging.Logger
+}
+
+// NewAuthenticator accepts API keys, HMAC signed requests and, when tokens
+// is not nil, bearer tokens.
+func NewAuthenticator(repository Repository, tracker *UsageTracker, tokens *TokenService, signatures *httpsign.Verifier, logger *logging.Logger) *Authenticator {
+	return &Authenticator{
+		repository: repository,
+		tracker:    tracker,
+		tokens:     tokens,
+		signatures: signatures,
+		keys:       newKeyCache(repository),
+		logger:     logger,
+	}
+}
+
+// Middleware resolves the API key sent in the X-API-Key header or as
+// "Authorization: ApiKey <key>", a bearer token or a request signature made
+// with the secret of an API key, and stores the principal
+// in the request context. Requests without credentials pass through
+// anonymously; invalid, expired and revoked credentials get 401.
+func (au *Authenticator) Middleware() handlers.Middleware {
+	return func(next httprouter.Handle) httprouter.Handle {
+		return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+			if httpsign.IsSigned(r) {
+				au.authenticateSignature(w, r, params, next)
+				return
+			}
+
+			if token := bearerToken(r); token != "" && au.tokens != nil {
+				a, err := au.authenticateToken(r.Context(), token)
+				if err != nil {
+					if isCredentialError(err) {
+						w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+						handlers.WriteError(w, http.StatusUnauthorized, err.Error())
+						return
+					}
+					au.logger.Error(err)
+					handlers.WriteError(w, http.StatusInternalServerError, "authentication failed")
+					return
+				}
+				next(w, r.WithContext(withPrincipal(r.Context(), a, MethodBearer)), params)
+				return
+			}
+
+			key := apiKeyFromRequest(r)
+			if key == "" {
+				next(w, r, params)
+				return
+			}
+
+			a, err := au.authenticateKey(r.Context(), key)
+			if err != nil {
+				if isCredentialError(err) {
+					handlers.WriteError(w, http.StatusUnauthorized, err.Error())
+					return
+				}
+				au.logger.Error(err)
+				handlers.WriteError(w, http.StatusInternalServerError, "authentication failed")
+				return
+			}
+
+			next(w, r.WithContext(withPrincipal(r.Context(), a, MethodAPIKey)), params)
+		}
+	}
+}
+
+func (au *Authenticator) authenticateSignature(w http.ResponseWriter, r *http.Request, params httprouter.Params, next httprouter.Handle) {
+	a, err := au.repository.FindOne(r.Context(), httpsign.KeyID(r))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			handlers.WriteError(w, http.StatusUnauthorized, "unknown signing key")
+			return
+		}
+		au.logger.Error(err)
+		handlers.WriteError(w, http.StatusInternalServerError, "authentication failed")
+		return
+	}
+
+	if a.SigningSecret == "" {
+		handlers.WriteError(w, http.StatusUnauthorized, "request signing is not enabled for this key")
+		return
+	}
+
+	now := time.Now()
+	if err = a.Validate(now); err != nil {
+		handlers.WriteError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if err = au.signatures.Verify(r, []byte(a.SigningSecret), now); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			handlers.WriteError(w, http.StatusRequestEntityTooLarge, "request body too large")
+			return
+		}
+		handlers.WriteError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	au.tracker.Touch(a.ID, now)
+
+	next(w, r.WithContext(withPrincipal(r.Context(), a, MethodSignature)), params)
+}
+
+// authenticateKey resolves an API key that may still be used and records
+// its use.
+func (au *Authenticator) authenticateKey(ctx context.Context, key string) (Auth, error) {
+	a, err := au.repository.FindByAPIKey(ctx, key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Auth{}, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return Auth{}, err
+	}
+
+	now := time.Now()
+	if err = a.Validate(now); err != nil {
+		return Auth{}, err
+	}
+	au.tracker.Touch(a.ID, now)
+	return a, nil
+}
+
+// authenticateToken resolves a bearer token whose key may still be used. The
+// principal gets the scopes of the token and the rate limit of the key.
+func (au *Authenticator) authenticateToken(ctx context.Context, token string) (Auth, error) {
+	a, err := au.tokens.Verify(token)
+	if err != nil {
+		return Auth{}, ErrInvalidToken
+	}
+
+	now := time.Now()
+	key, err := au.keys.get(ctx, a.ID, now)
+	if err != nil {
+		return Auth{}, err
+	}
+	if err = key.Validate(now); err != nil {
+		return Auth{}, err
+	}
+
+	a.RateLimit, a.RateBurst = key.RateLimit, key.RateBurst
+	return a, nil
+}
+
+// isCredentialError tells errors of the caller apart from storage failures.
+func isCredentialError(err error) bool {
+	return errors.Is(err, ErrInvalidAPIKey) || errors.Is(err, ErrInvalidToken) || errors.Is(err, ErrKeyExpired) || errors.Is(err, ErrKeyRevoked)
+}
+
+func withPrincipal(ctx context.Context, a Auth, method string) context.Context {
+	return context.WithValue(WithAuth(ctx, a), methodKey, method)
+}
+
+func apiKeyFromRequest(r *http.Request) string {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return key
+	}
+	scheme, key, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if ok && strings.EqualFold(scheme, "ApiKey") {
+		return strings.TrimSpace(key)
+	}
+	return ""
+}
+
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// RateLimitKey keys rate limiting by the authenticated API key and applies
+// the limit stored with the key, if any.
+func RateLimitKey(r *http.Request) (string, *ratelimit.Limit) {
+	a, ok := FromContext(r.Context())
+	if !ok {
+		return "", nil
+	}
+
+	key := "key:" + strconv.Itoa(a.ID)
+	if a.RateLimit <= 0 || a.RateBurst <= 0 {
+		return key, nil
+	}
+	return key, &ratelimit.Limit{Rate: a.RateLimit, Burst: a.RateBurst}
+}
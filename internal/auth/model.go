@@ -1,6 +1,58 @@
 package auth
 
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidAPIKey = errors.New("invalid API key")
+	ErrKeyExpired    = errors.New("API key expired")
+	ErrKeyRevoked    = errors.New("API key revoked")
+	ErrInvalidToken  = errors.New("invalid token")
+)
+
 type Auth struct {
-	ID     int    `json:"id"`
-	APIKey string `json:"api_key"`
+	ID     int      `json:"id"`
+	APIKey string   `json:"api_key"`
+	Scopes []string `json:"scopes"`
+	// SigningSecret enables HMAC request signing for the key when set.
+	SigningSecret string `json:"signing_secret,omitempty"`
+	// UserID is the owner of the key, nil for service keys.
+	UserID *int `json:"user_id,omitempty"`
+	// RateLimit and RateBurst override the global rate limit when set.
+	RateLimit  float64    `json:"rate_limit,omitempty"`
+	RateBurst  int        `json:"rate_burst,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Validate reports whether the key may still be used at now.
+func (a Auth) Validate(now time.Time) error {
+	if a.RevokedAt != nil && !a.RevokedAt.After(now) {
+		return ErrKeyRevoked
+	}
+	if a.ExpiresAt != nil && !a.ExpiresAt.After(now) {
+		return ErrKeyExpired
+	}
+	return nil
+}
+
+// Masked hides all but the last four characters of the key and the signing
+// secret.
+func (a Auth) Masked() Auth {
+	if len(a.APIKey) > 4 {
+		a.APIKey = "****" + a.APIKey[len(a.APIKey)-4:]
+	}
+	return a.WithoutSecret()
+}
+
+// WithoutSecret hides the signing secret, which is only shown on creation.
+func (a Auth) WithoutSecret() Auth {
+	if a.SigningSecret != "" {
+		a.SigningSecret = "****"
+	}
+	return a
 }
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"awesome-clean-arch/internal/handlers"
+	"context"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"strconv"
+)
+
+const (
+	ScopeUsersRead     = "users:read"
+	ScopeUsersWrite    = "users:write"
+	ScopeProfilesRead  = "profiles:read"
+	ScopeProfilesWrite = "profiles:write"
+	ScopeUserDataRead  = "user_data:read"
+	ScopeUserDataWrite = "user_data:write"
+	ScopeAuditRead     = "audit:read"
+	// ScopeWebhooksManage manages webhooks and reads their deliveries.
+	ScopeWebhooksManage = "webhooks:manage"
+	// ScopeAuthAdmin manages API keys and implies every other scope.
+	ScopeAuthAdmin = "auth:admin"
+)
+
+var KnownScopes = []string{
+	ScopeUsersRead,
+	ScopeUsersWrite,
+	ScopeProfilesRead,
+	ScopeProfilesWrite,
+	ScopeUserDataRead,
+	ScopeUserDataWrite,
+	ScopeAuditRead,
+	ScopeWebhooksManage,
+	ScopeAuthAdmin,
+}
+
+func (a Auth) HasScope(scope string) bool {
+	for _, s := range a.Scopes {
+		if s == scope || s == ScopeAuthAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+func IsKnownScope(scope string) bool {
+	for _, s := range KnownScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireScope answers 401 to anonymous requests and 403 to keys lacking scope.
+func RequireScope(scope string) handlers.Middleware {
+	return func(next httprouter.Handle) httprouter.Handle {
+		return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+			a, ok := FromContext(r.Context())
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `ApiKey realm="api"`)
+				handlers.WriteError(w, http.StatusUnauthorized, "API key required")
+				return
+			}
+			if !a.HasScope(scope) {
+				handlers.WriteError(w, http.StatusForbidden, "missing scope "+scope)
+				return
+			}
+			next(w, r, params)
+		}
+	}
+}
+
+// IncludeDeleted reads the include_deleted query parameter. Only admin keys
+// may see soft-deleted rows, ok is false when anyone else asks for them.
+func IncludeDeleted(r *http.Request) (include bool, ok bool) {
+	include, _ = strconv.ParseBool(r.URL.Query().Get("include_deleted"))
+	if !include {
+		return false, true
+	}
+	return true, IsAdmin(r.Context())
+}
+
+// IsAdmin reports whether the principal of ctx has scope auth:admin.
+func IsAdmin(ctx context.Context) bool {
+	a, ok := FromContext(ctx)
+	return ok && a.HasScope(ScopeAuthAdmin)
+}
@@ -1,11 +1,19 @@
 package auth
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type Repository interface {
 	Create(ctx context.Context, auth Auth) (string, error)
 	FindAll(ctx context.Context) (a []Auth, err error)
 	FindOne(ctx context.Context, id string) (Auth, error)
+	FindByAPIKey(ctx context.Context, apiKey string) (Auth, error)
+	FindByUserID(ctx context.Context, userID string) ([]Auth, error)
+	FindByUserIDs(ctx context.Context, userIDs []string) ([]Auth, error)
 	Update(ctx context.Context, auth Auth) error
+	Revoke(ctx context.Context, id string, at time.Time) error
+	UpdateLastUsed(ctx context.Context, usage map[int]time.Time) error
 	Delete(ctx context.Context, id string) error
 }
@@ -0,0 +1,267 @@
+package auth
+
+import (
+	"awesome-clean-arch/internal/config"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Claims are the claims of tokens issued in exchange for an API key.
+type Claims struct {
+	jwt.RegisteredClaims
+	KeyID  int    `json:"key_id"`
+	UserID *int   `json:"uid,omitempty"`
+	Scope  string `json:"scope"`
+}
+
+type tokenKey struct {
+	id     string
+	method jwt.SigningMethod
+	sign   interface{}
+	verify interface{}
+}
+
+type TokenService struct {
+	issuer  string
+	ttl     time.Duration
+	signing *tokenKey
+	keys    map[string]*tokenKey
+}
+
+// NewTokenService returns nil when no keys are configured.
+func NewTokenService(cfg config.JWTConfig) (*TokenService, error) {
+	if len(cfg.Keys) == 0 {
+		return nil, nil
+	}
+
+	s := &TokenService{
+		issuer: cfg.Issuer,
+		ttl:    cfg.TTL,
+		keys:   make(map[string]*tokenKey, len(cfg.Keys)),
+	}
+
+	for _, kc := range cfg.Keys {
+		k, err := loadTokenKey(kc)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %q: %w", kc.ID, err)
+		}
+		s.keys[k.id] = k
+	}
+
+	s.signing = s.keys[cfg.SigningKeyID]
+	if s.signing == nil || s.signing.sign == nil {
+		return nil, fmt.Errorf("jwt signing key %q is not usable for signing", cfg.SigningKeyID)
+	}
+
+	return s, nil
+}
+
+func (s *TokenService) TTL() time.Duration {
+	return s.ttl
+}
+
+// Issue signs a token carrying the key's owner and scopes. The requested
+// scopes, if any, narrow the key's scopes.
+func (s *TokenService) Issue(a Auth, scopes []string, now time.Time) (string, time.Time, error) {
+	granted := a.Scopes
+	if len(scopes) > 0 {
+		granted = make([]string, 0, len(scopes))
+		for _, sc := range scopes {
+			if !a.HasScope(sc) {
+				return "", time.Time{}, fmt.Errorf("API key lacks scope %s", sc)
+			}
+			granted = append(granted, sc)
+		}
+	}
+
+	expiresAt := now.Add(s.ttl)
+	if a.ExpiresAt != nil && a.ExpiresAt.Before(expiresAt) {
+		expiresAt = *a.ExpiresAt
+	}
+
+	jti, err := generateAPIKey()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    s.issuer,
+			Subject:   "key:" + strconv.Itoa(a.ID),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			ID:        jti,
+		},
+		KeyID:  a.ID,
+		UserID: a.UserID,
+		Scope:  strings.Join(granted, " "),
+	}
+
+	token := jwt.NewWithClaims(s.signing.method, claims)
+	token.Header["kid"] = s.signing.id
+
+	signed, err := token.SignedString(s.signing.sign)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
+}
+
+// Verify checks the signature with the key named by the kid header and
+// returns the principal the token was issued for.
+func (s *TokenService) Verify(tokenString string) (Auth, error) {
+	claims := &Claims{}
+
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		k, ok := s.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+		if t.Method.Alg() != k.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+		}
+		return k.verify, nil
+	}, jwt.WithIssuer(s.issuer), jwt.WithExpirationRequired())
+	if err != nil {
+		return Auth{}, err
+	}
+
+	a := Auth{
+		ID:     claims.KeyID,
+		UserID: claims.UserID,
+		Scopes: strings.Fields(claims.Scope),
+	}
+	if claims.ExpiresAt != nil {
+		a.ExpiresAt = &claims.ExpiresAt.Time
+	}
+	return a, nil
+}
+
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS publishes the public keys. Shared HS256 secrets are never exposed.
+func (s *TokenService) JWKS() JWKSet {
+	set := JWKSet{Keys: make([]JWK, 0, len(s.keys))}
+
+	for _, k := range s.keys {
+		switch pub := k.verify.(type) {
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				KeyType:   "OKP",
+				KeyID:     k.id,
+				Algorithm: k.method.Alg(),
+				Use:       "sig",
+				Curve:     "Ed25519",
+				X:         base64.RawURLEncoding.EncodeToString(pub),
+			})
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				KeyType:   "RSA",
+				KeyID:     k.id,
+				Algorithm: k.method.Alg(),
+				Use:       "sig",
+				N:         base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		}
+	}
+
+	return set
+}
+
+func loadTokenKey(kc config.JWTKey) (*tokenKey, error) {
+	k := &tokenKey{id: kc.ID}
+
+	switch kc.Algorithm {
+	case "HS256":
+		k.method = jwt.SigningMethodHS256
+		k.sign = []byte(kc.Secret)
+		k.verify = []byte(kc.Secret)
+		return k, nil
+	case "EdDSA":
+		k.method = jwt.SigningMethodEdDSA
+	case "RS256":
+		k.method = jwt.SigningMethodRS256
+	default:
+		return nil, fmt.Errorf("unknown algorithm %q", kc.Algorithm)
+	}
+
+	if kc.PrivateKeyFile != "" {
+		block, err := readPEM(kc.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		priv, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := priv.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("unsupported private key")
+		}
+		k.sign = priv
+		k.verify = signer.Public()
+	} else {
+		block, err := readPEM(kc.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if k.verify, err = x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+			return nil, err
+		}
+	}
+
+	switch k.verify.(type) {
+	case ed25519.PublicKey:
+		if kc.Algorithm != "EdDSA" {
+			return nil, errors.New("Ed25519 key requires the EdDSA algorithm")
+		}
+	case *rsa.PublicKey:
+		if kc.Algorithm != "RS256" {
+			return nil, errors.New("RSA key requires the RS256 algorithm")
+		}
+	default:
+		return nil, errors.New("unsupported key type")
+	}
+
+	return k, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("%s contains no PEM data", path)
+	}
+	return block, nil
+}
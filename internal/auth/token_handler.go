@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"awesome-clean-arch/internal/handlers"
+	"awesome-clean-arch/pkg/logging"
+	"encoding/json"
+	"github.com/julienschmidt/httprouter"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	tokenURL = "/token"
+	jwksURL  = "/.well-known/jwks.json"
+)
+
+var _ handlers.Handler = &tokenHandler{}
+
+type TokenRequest struct {
+	// Scope optionally narrows the token to a space separated subset of the key's scopes.
+	Scope string `json:"scope"`
+}
+
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+type tokenHandler struct {
+	logger *logging.Logger
+	tokens *TokenService
+}
+
+func NewTokenHandler(logger *logging.Logger, tokens *TokenService) handlers.Handler {
+	return &tokenHandler{
+		logger: logger,
+		tokens: tokens,
+	}
+}
+
+func (h *tokenHandler) Middleware() []handlers.Middleware {
+	return nil
+}
+
+func (h *tokenHandler) Register(router handlers.Router) {
+	// The response carries the bearer token, it must not be kept for replay.
+	router.With(handlers.NoReplay).POST(tokenURL, h.CreateToken)
+	router.GET(jwksURL, h.GetJWKS)
+}
+
+func (h *tokenHandler) Docs() []handlers.Doc {
+	tags := []string{"tokens"}
+	return []handlers.Doc{
+		{Method: http.MethodPost, Path: tokenURL, Summary: "Exchange an API key for a bearer token", Tags: tags,
+			Description: "Must be called with an API key, not with a token.", Request: TokenRequest{}, Response: TokenResponse{}},
+		{Method: http.MethodGet, Path: jwksURL, Summary: "Public keys that verify bearer tokens", Tags: tags, Response: JWKSet{}},
+	}
+}
+
+// CreateToken exchanges a valid API key for a short-lived bearer token.
+func (h *tokenHandler) CreateToken(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	a, ok := FromContext(r.Context())
+	if !ok || MethodFromContext(r.Context()) != MethodAPIKey {
+		w.Header().Set("WWW-Authenticate", `ApiKey realm="api"`)
+		handlers.WriteError(w, http.StatusUnauthorized, "API key required")
+		return
+	}
+
+	var req TokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		handlers.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	now := time.Now()
+	token, expiresAt, err := h.tokens.Issue(a, strings.Fields(req.Scope), now)
+	if err != nil {
+		handlers.WriteError(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	claims, err := h.tokens.Verify(token)
+	if err != nil {
+		h.logger.Error(err)
+		handlers.WriteError(w, http.StatusInternalServerError, "token issuance failed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(TokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(expiresAt.Sub(now).Seconds()),
+		Scope:       strings.Join(claims.Scopes, " "),
+	})
+}
+
+func (h *tokenHandler) GetJWKS(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.tokens.JWKS())
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"awesome-clean-arch/pkg/logging"
+	"context"
+	"sync"
+	"time"
+)
+
+// UsageTracker collects key usage in memory and writes last_used_at in
+// batches, so authenticating a request does not cost a database write.
+type UsageTracker struct {
+	repository Repository
+	interval   time.Duration
+	logger     *logging.Logger
+
+	mu      sync.Mutex
+	pending map[int]time.Time
+}
+
+func NewUsageTracker(repository Repository, interval time.Duration, logger *logging.Logger) *UsageTracker {
+	return &UsageTracker{
+		repository: repository,
+		interval:   interval,
+		logger:     logger,
+		pending:    make(map[int]time.Time),
+	}
+}
+
+func (t *UsageTracker) Touch(id int, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending[id] = at
+}
+
+// Run flushes pending usage every interval and once more when ctx is done.
+func (t *UsageTracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			t.flush(flushCtx)
+			cancel()
+			return
+		case <-ticker.C:
+			t.flush(ctx)
+		}
+	}
+}
+
+func (t *UsageTracker) flush(ctx context.Context) {
+	t.mu.Lock()
+	if len(t.pending) == 0 {
+		t.mu.Unlock()
+		return
+	}
+	batch := t.pending
+	t.pending = make(map[int]time.Time, len(batch))
+	t.mu.Unlock()
+
+	if err := t.repository.UpdateLastUsed(ctx, batch); err != nil {
+		t.logger.Errorf("failed to update API key usage: %s", err)
+
+		// Put the batch back unless newer usage was recorded meanwhile.
+		t.mu.Lock()
+		for id, at := range batch {
+			if _, ok := t.pending[id]; !ok {
+				t.pending[id] = at
+			}
+		}
+		t.mu.Unlock()
+	}
+}
@@ -2,41 +2,338 @@ package config
 
 import (
 	"awesome-clean-arch/pkg/logging"
+	"errors"
+	"flag"
+	"fmt"
 	"github.com/ilyakaznacheev/cleanenv"
+	"gopkg.in/yaml.v3"
+	"os"
 	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultConfigPath = "config.yml"
+	configPathEnv     = "APP_CONFIG"
 )
 
 type Config struct {
-	IsDebug *bool `yaml:"is_debug" env-required:"true"`
-	Listen  struct {
-		Type   string `yaml:"type" env-default:"port"`
-		BindIP string `yaml:"bind_ip" env-default:"127.0.0.1"`
-		Port   string `yaml:"port" env-default:"8080"`
-	} `yaml:"listen"`
-	Storage StorageConfig `yaml:"storage"`
+	IsDebug     *bool             `yaml:"is_debug" env:"APP_IS_DEBUG" env-required:"true" env-description:"Enable debug mode"`
+	Listen      ListenConfig      `yaml:"listen"`
+	HTTP        HTTPConfig        `yaml:"http"`
+	Storage     StorageConfig     `yaml:"storage"`
+	Secrets     SecretsConfig     `yaml:"secrets"`
+	Log         LogConfig         `yaml:"log"`
+	CORS        CORSConfig        `yaml:"cors"`
+	RateLimit   RateLimitConfig   `yaml:"rate_limit"`
+	JWT         JWTConfig         `yaml:"jwt"`
+	Signing     SigningConfig     `yaml:"request_signing"`
+	Deletion    DeletionConfig    `yaml:"soft_delete"`
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+	API         APIConfig         `yaml:"api"`
+	GRPC        GRPCConfig        `yaml:"grpc"`
+	GraphQL     GraphQLConfig     `yaml:"graphql"`
+	Events      EventsConfig      `yaml:"events"`
+	Webhooks    WebhooksConfig    `yaml:"webhooks"`
+	Outbox      OutboxConfig      `yaml:"outbox"`
+	Features    map[string]bool   `yaml:"features" env:"APP_FEATURES" env-description:"Feature flags, e.g. name1:true,name2:false"`
+}
+
+type ListenConfig struct {
+	Type   string    `yaml:"type" env:"APP_LISTEN_TYPE" env-default:"port" env-description:"Listener type: port or sock"`
+	BindIP string    `yaml:"bind_ip" env:"APP_LISTEN_BIND_IP" env-default:"127.0.0.1" env-description:"IP address to bind"`
+	Port   string    `yaml:"port" env:"APP_LISTEN_PORT" env-default:"8080" env-description:"TCP port to listen on"`
+	TLS    TLSConfig `yaml:"tls"`
+	// Socket settings apply to the sock listener type only.
+	SocketPath  string `yaml:"socket_path" env:"APP_LISTEN_SOCKET_PATH" env-description:"Unix socket path, defaults to app.sock next to the binary"`
+	SocketMode  string `yaml:"socket_mode" env:"APP_LISTEN_SOCKET_MODE" env-default:"0660" env-description:"Unix socket file mode in octal"`
+	SocketGroup string `yaml:"socket_group" env:"APP_LISTEN_SOCKET_GROUP" env-description:"Group name or id owning the unix socket"`
+}
+
+type TLSConfig struct {
+	Enabled  bool   `yaml:"enabled" env:"APP_LISTEN_TLS_ENABLED" env-description:"Serve HTTPS instead of plain HTTP"`
+	CertFile string `yaml:"cert_file" env:"APP_LISTEN_TLS_CERT_FILE" env-description:"Path to the PEM encoded server certificate chain"`
+	KeyFile  string `yaml:"key_file" env:"APP_LISTEN_TLS_KEY_FILE" env-description:"Path to the PEM encoded server private key"`
+	// ClientCAFile enables mutual TLS when set.
+	ClientCAFile string   `yaml:"client_ca_file" env:"APP_LISTEN_TLS_CLIENT_CA_FILE" env-description:"Path to the PEM encoded CA bundle used to verify client certificates"`
+	ClientAuth   string   `yaml:"client_auth" env:"APP_LISTEN_TLS_CLIENT_AUTH" env-default:"require" env-description:"Client certificate policy with a client CA: request, verify_if_given or require"`
+	MinVersion   string   `yaml:"min_version" env:"APP_LISTEN_TLS_MIN_VERSION" env-default:"1.2" env-description:"Minimum TLS version: 1.0, 1.1, 1.2 or 1.3"`
+	CipherSuites []string `yaml:"cipher_suites" env:"APP_LISTEN_TLS_CIPHER_SUITES" env-description:"Allowed TLS 1.0-1.2 cipher suites, e.g. TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"`
+}
+
+type HTTPConfig struct {
+	RequestTimeout time.Duration `yaml:"request_timeout" env:"APP_HTTP_REQUEST_TIMEOUT" env-default:"10s" env-description:"Maximum time to handle a request"`
+	MaxBodySize    int64         `yaml:"max_body_size" env:"APP_HTTP_MAX_BODY_SIZE" env-default:"1048576" env-description:"Maximum request body size in bytes"`
 }
 
 type StorageConfig struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
-	Host     string `json:"host"`
-	Port     string `json:"port"`
-	Database string `json:"database"`
+	Username string `yaml:"username" json:"username" env:"APP_STORAGE_USERNAME" env-description:"Database user"`
+	Password string `yaml:"password" json:"password" env:"APP_STORAGE_PASSWORD" env-description:"Database password"`
+	// PasswordFile takes precedence over Password when set.
+	PasswordFile string `yaml:"password_file" json:"-" env:"APP_STORAGE_PASSWORD_FILE" env-description:"Path to a file holding the database password"`
+	Host         string `yaml:"host" json:"host" env:"APP_STORAGE_HOST" env-description:"Database host"`
+	Port         string `yaml:"port" json:"port" env:"APP_STORAGE_PORT" env-description:"Database port"`
+	Database     string `yaml:"database" json:"database" env:"APP_STORAGE_DATABASE" env-description:"Database name"`
+}
+
+type LogConfig struct {
+	Level string `yaml:"level" env:"APP_LOG_LEVEL" env-default:"trace" env-description:"Log level: panic, fatal, error, warn, info, debug or trace"`
+}
+
+type CORSConfig struct {
+	AllowedOrigins []string `yaml:"allowed_origins" env:"APP_CORS_ALLOWED_ORIGINS" env-description:"Origins allowed to make cross-origin requests, * for any"`
+	AllowedMethods []string `yaml:"allowed_methods" env:"APP_CORS_ALLOWED_METHODS" env-default:"GET,POST,PUT,PATCH,DELETE" env-description:"Methods allowed in cross-origin requests"`
+	AllowedHeaders []string `yaml:"allowed_headers" env:"APP_CORS_ALLOWED_HEADERS" env-default:"Content-Type,Authorization,X-API-Key" env-description:"Headers allowed in cross-origin requests"`
+	MaxAge         int      `yaml:"max_age" env:"APP_CORS_MAX_AGE" env-default:"600" env-description:"Preflight cache duration in seconds"`
+}
+
+type RateLimitConfig struct {
+	Enabled bool    `yaml:"enabled" env:"APP_RATE_LIMIT_ENABLED" env-description:"Enable request rate limiting"`
+	Rate    float64 `yaml:"rate" env:"APP_RATE_LIMIT_RATE" env-default:"10" env-description:"Requests per second allowed per client"`
+	Burst   int     `yaml:"burst" env:"APP_RATE_LIMIT_BURST" env-default:"20" env-description:"Maximum burst of requests per client"`
+}
+
+// JWTConfig enables bearer tokens when at least one key is configured.
+// Tokens are signed with SigningKeyID and verified with any listed key, so a
+// key can be rotated by adding the new one, switching SigningKeyID and
+// removing the old key once issued tokens have expired.
+type JWTConfig struct {
+	Issuer       string        `yaml:"issuer" env:"APP_JWT_ISSUER" env-default:"awesome-clean-arch" env-description:"JWT issuer claim"`
+	TTL          time.Duration `yaml:"ttl" env:"APP_JWT_TTL" env-default:"15m" env-description:"Lifetime of issued tokens"`
+	SigningKeyID string        `yaml:"signing_key_id" env:"APP_JWT_SIGNING_KEY_ID" env-description:"Id of the key used to sign new tokens"`
+	Keys         []JWTKey      `yaml:"keys"`
+}
+
+type JWTKey struct {
+	ID        string `yaml:"id"`
+	Algorithm string `yaml:"algorithm"`
+	// Secret is the HS256 shared secret, SecretFile takes precedence when set.
+	Secret     string `yaml:"secret"`
+	SecretFile string `yaml:"secret_file"`
+	// PrivateKeyFile is a PKCS#8 PEM key for EdDSA and RS256. Keys kept only
+	// for verification may set PublicKeyFile (PKIX PEM) instead.
+	PrivateKeyFile string `yaml:"private_key_file"`
+	PublicKeyFile  string `yaml:"public_key_file"`
+}
+
+type SigningConfig struct {
+	ClockSkew time.Duration `yaml:"clock_skew" env:"APP_REQUEST_SIGNING_CLOCK_SKEW" env-default:"5m" env-description:"Maximum difference between a signature timestamp and the server clock"`
+}
+
+// DeletionConfig controls how long soft-deleted users and profiles are kept.
+type DeletionConfig struct {
+	Retention     time.Duration `yaml:"retention" env:"APP_SOFT_DELETE_RETENTION" env-default:"720h" env-description:"How long soft-deleted rows are kept before being purged, 0 keeps them forever"`
+	PurgeInterval time.Duration `yaml:"purge_interval" env:"APP_SOFT_DELETE_PURGE_INTERVAL" env-default:"1h" env-description:"How often the purge job runs"`
+}
+
+type IdempotencyConfig struct {
+	TTL time.Duration `yaml:"ttl" env:"APP_IDEMPOTENCY_TTL" env-default:"24h" env-description:"How long responses to requests with an Idempotency-Key are kept for replay"`
+}
+
+type APIConfig struct {
+	Deprecated []DeprecatedVersion `yaml:"deprecated_versions"`
+}
+
+// DeprecatedVersion makes the routes of an API version, e.g. v1, announce
+// their removal with Deprecation and Sunset headers.
+type DeprecatedVersion struct {
+	Version string    `yaml:"version"`
+	Since   time.Time `yaml:"since"`
+	Sunset  time.Time `yaml:"sunset"`
+	Link    string    `yaml:"link"`
+}
+
+// GRPCConfig serves the gRPC API on its own port of listen.bind_ip, with the
+// TLS settings of listen.tls.
+type GRPCConfig struct {
+	Enabled    bool   `yaml:"enabled" env:"APP_GRPC_ENABLED" env-description:"Serve the gRPC API"`
+	Port       string `yaml:"port" env:"APP_GRPC_PORT" env-default:"9090" env-description:"TCP port of the gRPC API"`
+	Reflection bool   `yaml:"reflection" env:"APP_GRPC_REFLECTION" env-default:"true" env-description:"Register the gRPC server reflection service"`
+}
+
+// GraphQLConfig bounds the queries of /graphql, 0 disables a limit.
+type GraphQLConfig struct {
+	MaxDepth      int `yaml:"max_depth" env:"APP_GRAPHQL_MAX_DEPTH" env-default:"6" env-description:"Maximum nesting of fields in a GraphQL query"`
+	MaxComplexity int `yaml:"max_complexity" env:"APP_GRAPHQL_MAX_COMPLEXITY" env-default:"1000" env-description:"Maximum number of fields a GraphQL query may resolve, list fields count once per item"`
+}
+
+// EventsConfig sizes the in-memory log behind /events, subscribers resume
+// from it after a reconnect. Every instance fills its log by reading the
+// outbox every poll_interval. A message committed out of order is waited for
+// up to gap_timeout, so the events reach subscribers in the order of their ids.
+type EventsConfig struct {
+	BufferSize   int           `yaml:"buffer_size" env:"APP_EVENTS_BUFFER_SIZE" env-default:"1000" env-description:"Number of recent events kept for resuming subscribers"`
+	Heartbeat    time.Duration `yaml:"heartbeat" env:"APP_EVENTS_HEARTBEAT" env-default:"15s" env-description:"Interval of keep-alive messages on idle event streams"`
+	PollInterval time.Duration `yaml:"poll_interval" env:"APP_EVENTS_POLL_INTERVAL" env-default:"500ms" env-description:"How often new outbox messages are read into the event log"`
+	GapTimeout   time.Duration `yaml:"gap_timeout" env:"APP_EVENTS_GAP_TIMEOUT" env-default:"10s" env-description:"How long a missing outbox message is waited for before it is skipped"`
+}
+
+// WebhooksConfig tunes the delivery of webhooks. A failed delivery is retried
+// after backoff, doubled for every further attempt up to max_backoff, and is
+// dead after max_attempts.
+type WebhooksConfig struct {
+	MaxAttempts  int           `yaml:"max_attempts" env:"APP_WEBHOOKS_MAX_ATTEMPTS" env-default:"8" env-description:"Attempts before a webhook delivery is dead"`
+	Backoff      time.Duration `yaml:"backoff" env:"APP_WEBHOOKS_BACKOFF" env-default:"30s" env-description:"Delay before the first retry of a webhook delivery"`
+	MaxBackoff   time.Duration `yaml:"max_backoff" env:"APP_WEBHOOKS_MAX_BACKOFF" env-default:"1h" env-description:"Maximum delay between attempts of a webhook delivery"`
+	Timeout      time.Duration `yaml:"timeout" env:"APP_WEBHOOKS_TIMEOUT" env-default:"10s" env-description:"Timeout of a webhook request"`
+	PollInterval time.Duration `yaml:"poll_interval" env:"APP_WEBHOOKS_POLL_INTERVAL" env-default:"5s" env-description:"How often due webhook deliveries are looked up"`
+	Concurrency  int           `yaml:"concurrency" env:"APP_WEBHOOKS_CONCURRENCY" env-default:"4" env-description:"Webhook deliveries sent in parallel"`
 }
 
-var instance *Config
+// OutboxConfig tunes the relay publishing the changes stored in the outbox to
+// webhooks, domain event subscribers and file. A message that failed is
+// published again to the sinks that did not take it after backoff, doubled
+// for every further failure up to max_backoff, and is dead after
+// max_attempts. Published messages are deleted after retention, dead ones
+// are kept.
+type OutboxConfig struct {
+	Relay        bool          `yaml:"relay" env:"APP_OUTBOX_RELAY" env-default:"true" env-description:"Run the outbox relay, on one instance only to keep changes in order"`
+	PollInterval time.Duration `yaml:"poll_interval" env:"APP_OUTBOX_POLL_INTERVAL" env-default:"500ms" env-description:"How often pending outbox messages are looked up"`
+	BatchSize    int           `yaml:"batch_size" env:"APP_OUTBOX_BATCH_SIZE" env-default:"100" env-description:"Outbox messages read at once"`
+	Backoff      time.Duration `yaml:"backoff" env:"APP_OUTBOX_BACKOFF" env-default:"1s" env-description:"Delay before publishing a failed outbox message again"`
+	MaxBackoff   time.Duration `yaml:"max_backoff" env:"APP_OUTBOX_MAX_BACKOFF" env-default:"5m" env-description:"Maximum delay between attempts of an outbox message"`
+	MaxAttempts  int           `yaml:"max_attempts" env:"APP_OUTBOX_MAX_ATTEMPTS" env-default:"10" env-description:"Attempts before an outbox message is dead"`
+	Retention    time.Duration `yaml:"retention" env:"APP_OUTBOX_RETENTION" env-default:"24h" env-description:"How long published outbox messages are kept"`
+	File         string        `yaml:"file" env:"APP_OUTBOX_FILE" env-description:"NDJSON file the outbox messages are appended to, disabled when empty"`
+}
+
+var instance atomic.Pointer[Config]
+var loader *Loader
 var once sync.Once
 
+// GetConfig returns the configuration currently in effect. It is loaded on
+// first use and replaced on reload, so callers should not cache the result.
 func GetConfig() *Config {
 	once.Do(func() {
 		logger := logging.GetLogger()
 		logger.Info("Read application configuration")
-		instance = &Config{}
-		if err := cleanenv.ReadConfig("config.yml", instance); err != nil {
-			help, _ := cleanenv.GetDescription(instance, nil)
+		l, err := NewLoader(flag.CommandLine, os.Args[1:])
+		if err != nil {
+			logger.Fatal(err)
+		}
+		cfg, err := l.Load()
+		if err != nil {
+			help, _ := cleanenv.GetDescription(&Config{}, nil)
 			logger.Info(help)
 			logger.Fatal(err)
 		}
+		if err = cfg.Validate(); err != nil {
+			logger.Fatalf("invalid configuration:\n%s", err)
+		}
+		loader = l
+		instance.Store(cfg)
 	})
-	return instance
+	return instance.Load()
+}
+
+func FeatureEnabled(name string) bool {
+	return GetConfig().Features[name]
+}
+
+type Loader struct {
+	flags *flags
+}
+
+func NewLoader(fs *flag.FlagSet, args []string) (*Loader, error) {
+	f := bindFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	return &Loader{flags: f}, nil
+}
+
+// Load builds the configuration from the config file, environment variables
+// and command-line flags, in increasing order of precedence.
+func Load(fs *flag.FlagSet, args []string) (*Config, error) {
+	l, err := NewLoader(fs, args)
+	if err != nil {
+		return nil, err
+	}
+	return l.Load()
+}
+
+func (l *Loader) Path() string {
+	return l.flags.configPath()
+}
+
+func (l *Loader) Load() (*Config, error) {
+	raw, err := os.ReadFile(l.Path())
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := parse(raw, l.flags, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc yaml.Node
+	if err = yaml.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("config file parsing error: %w", err)
+	}
+
+	if hasSecretRefs(&doc) {
+		secrets, err := NewSecretProvider(cfg.Secrets)
+		if err != nil {
+			return nil, err
+		}
+		if secrets == nil {
+			return nil, errors.New("config file references secrets but no secret provider is configured")
+		}
+		if cfg, err = parse(raw, l.flags, secrets); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = cfg.resolveSecretFiles(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func parse(raw []byte, f *flags, secrets SecretProvider) (*Config, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("config file parsing error: %w", err)
+	}
+	if err := interpolate(&doc, secrets); err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{}
+	if len(doc.Content) > 0 {
+		if err := doc.Decode(cfg); err != nil {
+			return nil, fmt.Errorf("config file parsing error: %w", err)
+		}
+	}
+	if err := cleanenv.ReadEnv(cfg); err != nil {
+		return nil, err
+	}
+
+	f.apply(cfg)
+
+	return cfg, nil
+}
+
+func (c *Config) resolveSecretFiles() error {
+	if c.Storage.PasswordFile != "" {
+		password, err := readSecretFile(c.Storage.PasswordFile)
+		if err != nil {
+			return err
+		}
+		c.Storage.Password = password
+	}
+	for i, k := range c.JWT.Keys {
+		if k.SecretFile == "" {
+			continue
+		}
+		secret, err := readSecretFile(k.SecretFile)
+		if err != nil {
+			return err
+		}
+		c.JWT.Keys[i].Secret = secret
+	}
+	return nil
 }
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"github.com/ilyakaznacheev/cleanenv"
+	"os"
+	"strconv"
+)
+
+const precedenceHelp = `Configuration precedence (highest first):
+  1. command-line flags
+  2. environment variables
+  3. config file (-config or APP_CONFIG, default "config.yml")
+  4. built-in defaults
+`
+
+type stringFlag struct {
+	name   string
+	usage  string
+	target func(cfg *Config) *string
+}
+
+var stringFlags = []stringFlag{
+	{"listen.type", "listener type: port or sock", func(cfg *Config) *string { return &cfg.Listen.Type }},
+	{"listen.bind-ip", "IP address to bind", func(cfg *Config) *string { return &cfg.Listen.BindIP }},
+	{"listen.port", "TCP port to listen on", func(cfg *Config) *string { return &cfg.Listen.Port }},
+	{"storage.host", "database host", func(cfg *Config) *string { return &cfg.Storage.Host }},
+	{"storage.port", "database port", func(cfg *Config) *string { return &cfg.Storage.Port }},
+	{"storage.database", "database name", func(cfg *Config) *string { return &cfg.Storage.Database }},
+	{"storage.username", "database user", func(cfg *Config) *string { return &cfg.Storage.Username }},
+}
+
+type flags struct {
+	fs      *flag.FlagSet
+	config  *string
+	debug   *bool
+	strings map[string]*string
+}
+
+func bindFlags(fs *flag.FlagSet) *flags {
+	f := &flags{
+		fs:      fs,
+		config:  fs.String("config", "", fmt.Sprintf("path to the config file (env %s, default %q)", configPathEnv, defaultConfigPath)),
+		debug:   fs.Bool("debug", false, "enable debug mode"),
+		strings: make(map[string]*string, len(stringFlags)),
+	}
+	for _, sf := range stringFlags {
+		f.strings[sf.name] = fs.String(sf.name, "", sf.usage)
+	}
+
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "Usage of %s:\n", fs.Name())
+		fs.PrintDefaults()
+		fmt.Fprintf(out, "\n%s\n", precedenceHelp)
+		cleanenv.FUsage(out, &Config{}, nil)()
+	}
+
+	return f
+}
+
+func (f *flags) configPath() string {
+	if *f.config != "" {
+		return *f.config
+	}
+	if p, ok := os.LookupEnv(configPathEnv); ok && p != "" {
+		return p
+	}
+	return defaultConfigPath
+}
+
+// apply overrides cfg with the flags that were explicitly set on the command line.
+func (f *flags) apply(cfg *Config) {
+	targets := make(map[string]func(cfg *Config) *string, len(stringFlags))
+	for _, sf := range stringFlags {
+		targets[sf.name] = sf.target
+	}
+
+	f.fs.Visit(func(fl *flag.Flag) {
+		if fl.Name == "debug" {
+			debug, _ := strconv.ParseBool(fl.Value.String())
+			cfg.IsDebug = &debug
+			return
+		}
+		if target, ok := targets[fl.Name]; ok {
+			*target(cfg) = *f.strings[fl.Name]
+		}
+	})
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const secretRefPrefix = "secret:"
+
+var placeholder = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// interpolate replaces ${VAR}, ${VAR:-default} and ${secret:name} placeholders
+// in the scalars of a parsed document, so comments are ignored and substituted
+// values are never parsed as YAML. Secret references are left untouched when
+// secrets is nil.
+func interpolate(doc *yaml.Node, secrets SecretProvider) error {
+	var firstErr error
+
+	walkScalars(doc, func(n *yaml.Node) {
+		value := placeholder.ReplaceAllStringFunc(n.Value, func(m string) string {
+			expr := m[2 : len(m)-1]
+
+			if strings.HasPrefix(expr, secretRefPrefix) {
+				if secrets == nil {
+					return m
+				}
+				s, err := secrets.GetSecret(strings.TrimPrefix(expr, secretRefPrefix))
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				return s
+			}
+
+			name, def, _ := strings.Cut(expr, ":-")
+			if v, ok := os.LookupEnv(name); ok && v != "" {
+				return v
+			}
+			return def
+		})
+		if value == n.Value {
+			return
+		}
+
+		n.Value = value
+		// A plain scalar is typed by its substituted value, e.g. a port.
+		if n.Style == 0 {
+			n.Tag = ""
+		}
+	})
+
+	if firstErr != nil {
+		return fmt.Errorf("interpolate config: %w", firstErr)
+	}
+	return nil
+}
+
+func hasSecretRefs(doc *yaml.Node) bool {
+	found := false
+	walkScalars(doc, func(n *yaml.Node) {
+		for _, m := range placeholder.FindAllStringSubmatch(n.Value, -1) {
+			if strings.HasPrefix(m[1], secretRefPrefix) {
+				found = true
+			}
+		}
+	})
+	return found
+}
+
+func walkScalars(n *yaml.Node, fn func(n *yaml.Node)) {
+	if n.Kind == yaml.ScalarNode {
+		fn(n)
+	}
+	for _, c := range n.Content {
+		walkScalars(c, fn)
+	}
+}
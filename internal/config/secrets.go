@@ -0,0 +1,165 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+const secretKeyEnv = "APP_SECRETS_KEY"
+
+var ErrSecretNotFound = errors.New("secret not found")
+
+// SecretProvider resolves ${secret:name} references found in the config file.
+type SecretProvider interface {
+	GetSecret(name string) (string, error)
+}
+
+type SecretsConfig struct {
+	Provider string `yaml:"provider" env:"APP_SECRETS_PROVIDER" env-description:"Secret provider: empty or file"`
+	Path     string `yaml:"path" env:"APP_SECRETS_PATH" env-default:"secrets.enc" env-description:"Path to the encrypted secrets file"`
+	KeyFile  string `yaml:"key_file" env:"APP_SECRETS_KEY_FILE" env-description:"Path to the file holding the hex encoded secrets key (alternative to APP_SECRETS_KEY)"`
+}
+
+func NewSecretProvider(sc SecretsConfig) (SecretProvider, error) {
+	switch sc.Provider {
+	case "":
+		return nil, nil
+	case "file":
+		key, err := loadSecretKey(sc.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		return NewEncryptedFileProvider(sc.Path, key), nil
+	default:
+		return nil, fmt.Errorf("unknown secret provider %q", sc.Provider)
+	}
+}
+
+func loadSecretKey(keyFile string) ([]byte, error) {
+	encoded := os.Getenv(secretKeyEnv)
+	if keyFile != "" {
+		b, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read secrets key: %w", err)
+		}
+		encoded = string(b)
+	}
+	if encoded == "" {
+		return nil, fmt.Errorf("secrets key is not set, use %s or secrets.key_file", secretKeyEnv)
+	}
+
+	key, err := hex.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("decode secrets key: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("secrets key must be 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
+
+// encryptedFileProvider reads a JSON object of secrets sealed with AES-256-GCM.
+// The file is decrypted lazily on first access.
+type encryptedFileProvider struct {
+	path    string
+	key     []byte
+	once    sync.Once
+	secrets map[string]string
+	err     error
+}
+
+func NewEncryptedFileProvider(path string, key []byte) SecretProvider {
+	return &encryptedFileProvider{
+		path: path,
+		key:  key,
+	}
+}
+
+func (p *encryptedFileProvider) GetSecret(name string) (string, error) {
+	p.once.Do(func() {
+		var data []byte
+		data, p.err = os.ReadFile(p.path)
+		if p.err != nil {
+			return
+		}
+		p.secrets, p.err = OpenSecrets(p.key, data)
+	})
+	if p.err != nil {
+		return "", p.err
+	}
+
+	s, ok := p.secrets[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+	}
+	return s, nil
+}
+
+// SealSecrets encrypts secrets into the format read by the file provider.
+func SealSecrets(key []byte, secrets map[string]string) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	plain, err := json.Marshal(secrets)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, plain, nil), nil
+}
+
+func OpenSecrets(key []byte, data []byte) (map[string]string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("secrets file is too short")
+	}
+
+	nonce, sealed := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt secrets: %w", err)
+	}
+
+	secrets := make(map[string]string)
+	if err = json.Unmarshal(plain, &secrets); err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// readSecretFile returns the trimmed content of a mounted secret file,
+// e.g. /run/secrets/db_password.
+func readSecretFile(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read secret file: %w", err)
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
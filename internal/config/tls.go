@@ -0,0 +1,57 @@
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+var tlsClientAuth = map[string]tls.ClientAuthType{
+	"request":         tls.RequestClientCert,
+	"verify_if_given": tls.VerifyClientCertIfGiven,
+	"require":         tls.RequireAndVerifyClientCert,
+}
+
+func TLSVersion(v string) (uint16, error) {
+	version, ok := tlsVersions[v]
+	if !ok {
+		return 0, fmt.Errorf("unknown TLS version %q", v)
+	}
+	return version, nil
+}
+
+func TLSClientAuth(policy string) (tls.ClientAuthType, error) {
+	auth, ok := tlsClientAuth[policy]
+	if !ok {
+		return tls.NoClientCert, fmt.Errorf("unknown client auth policy %q", policy)
+	}
+	return auth, nil
+}
+
+// TLSCipherSuites maps cipher suite names to their IDs. Insecure suites are rejected.
+func TLSCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	known := make(map[string]uint16)
+	for _, cs := range tls.CipherSuites() {
+		known[cs.Name] = cs.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown or insecure cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
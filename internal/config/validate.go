@@ -0,0 +1,233 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"net"
+	"strconv"
+)
+
+const masked = "******"
+
+// Validate reports every semantic problem of the configuration at once.
+func (c *Config) Validate() error {
+	var errs []error
+
+	switch c.Listen.Type {
+	case "port":
+		if c.Listen.BindIP != "" && net.ParseIP(c.Listen.BindIP) == nil {
+			errs = append(errs, fmt.Errorf("listen.bind_ip: %q is not a valid IP address", c.Listen.BindIP))
+		}
+		if err := validatePort(c.Listen.Port); err != nil {
+			errs = append(errs, fmt.Errorf("listen.port: %w", err))
+		}
+	case "sock":
+		if _, err := strconv.ParseUint(c.Listen.SocketMode, 8, 32); err != nil {
+			errs = append(errs, fmt.Errorf("listen.socket_mode: %q is not an octal file mode", c.Listen.SocketMode))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("listen.type: unknown type %q, expected port or sock", c.Listen.Type))
+	}
+
+	errs = append(errs, c.Listen.TLS.validate()...)
+
+	if c.HTTP.RequestTimeout < 0 || c.HTTP.MaxBodySize < 0 {
+		errs = append(errs, errors.New("http: request_timeout and max_body_size must not be negative"))
+	}
+
+	if c.Storage.Host == "" {
+		errs = append(errs, errors.New("storage.host: is required"))
+	}
+	if err := validatePort(c.Storage.Port); err != nil {
+		errs = append(errs, fmt.Errorf("storage.port: %w", err))
+	}
+	if c.Storage.Database == "" {
+		errs = append(errs, errors.New("storage.database: is required"))
+	}
+	if c.Storage.Username == "" {
+		errs = append(errs, errors.New("storage.username: is required"))
+	}
+
+	if _, err := logrus.ParseLevel(c.Log.Level); err != nil {
+		errs = append(errs, fmt.Errorf("log.level: %w", err))
+	}
+	if c.RateLimit.Enabled && (c.RateLimit.Rate <= 0 || c.RateLimit.Burst < 1) {
+		errs = append(errs, errors.New("rate_limit: rate and burst must be positive"))
+	}
+	if c.CORS.MaxAge < 0 {
+		errs = append(errs, errors.New("cors.max_age: must not be negative"))
+	}
+
+	errs = append(errs, c.JWT.validate()...)
+	errs = append(errs, c.API.validate()...)
+	if c.GRPC.Enabled {
+		if err := validatePort(c.GRPC.Port); err != nil {
+			errs = append(errs, fmt.Errorf("grpc.port: %w", err))
+		} else if c.Listen.Type == "port" && c.GRPC.Port == c.Listen.Port {
+			errs = append(errs, errors.New("grpc.port: must differ from listen.port"))
+		}
+	}
+	if c.GraphQL.MaxDepth < 0 || c.GraphQL.MaxComplexity < 0 {
+		errs = append(errs, errors.New("graphql: max_depth and max_complexity must not be negative"))
+	}
+	if c.Events.BufferSize < 1 {
+		errs = append(errs, errors.New("events.buffer_size: must be positive"))
+	}
+	if c.Events.Heartbeat <= 0 || c.Events.PollInterval <= 0 || c.Events.GapTimeout <= 0 {
+		errs = append(errs, errors.New("events: heartbeat, poll_interval and gap_timeout must be positive"))
+	}
+	if c.Webhooks.MaxAttempts < 1 || c.Webhooks.Concurrency < 1 {
+		errs = append(errs, errors.New("webhooks: max_attempts and concurrency must be positive"))
+	}
+	if c.Webhooks.Backoff <= 0 || c.Webhooks.MaxBackoff < c.Webhooks.Backoff {
+		errs = append(errs, errors.New("webhooks: backoff must be positive and not exceed max_backoff"))
+	}
+	if c.Webhooks.Timeout <= 0 || c.Webhooks.PollInterval <= 0 {
+		errs = append(errs, errors.New("webhooks: timeout and poll_interval must be positive"))
+	}
+	if c.Outbox.PollInterval <= 0 || c.Outbox.BatchSize < 1 || c.Outbox.MaxAttempts < 1 {
+		errs = append(errs, errors.New("outbox: poll_interval, batch_size and max_attempts must be positive"))
+	}
+	if c.Outbox.Backoff <= 0 || c.Outbox.MaxBackoff < c.Outbox.Backoff {
+		errs = append(errs, errors.New("outbox: backoff must be positive and not exceed max_backoff"))
+	}
+	if c.Outbox.Retention < 0 {
+		errs = append(errs, errors.New("outbox.retention: must not be negative"))
+	}
+	if c.Signing.ClockSkew <= 0 {
+		errs = append(errs, errors.New("request_signing.clock_skew: must be positive"))
+	}
+	if c.Deletion.Retention < 0 {
+		errs = append(errs, errors.New("soft_delete.retention: must not be negative"))
+	}
+	if c.Deletion.PurgeInterval <= 0 {
+		errs = append(errs, errors.New("soft_delete.purge_interval: must be positive"))
+	}
+	if c.Idempotency.TTL <= 0 {
+		errs = append(errs, errors.New("idempotency.ttl: must be positive"))
+	}
+
+	switch c.Secrets.Provider {
+	case "", "file":
+	default:
+		errs = append(errs, fmt.Errorf("secrets.provider: unknown provider %q", c.Secrets.Provider))
+	}
+
+	return errors.Join(errs...)
+}
+
+func validatePort(port string) error {
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		return fmt.Errorf("%q is not a number", port)
+	}
+	if p < 1 || p > 65535 {
+		return fmt.Errorf("%d is out of range 1-65535", p)
+	}
+	return nil
+}
+
+// Masked returns a copy of the configuration that is safe to print.
+func (c *Config) Masked() Config {
+	m := *c
+	if m.Storage.Password != "" {
+		m.Storage.Password = masked
+	}
+	m.JWT.Keys = make([]JWTKey, len(c.JWT.Keys))
+	for i, k := range c.JWT.Keys {
+		if k.Secret != "" {
+			k.Secret = masked
+		}
+		m.JWT.Keys[i] = k
+	}
+	return m
+}
+
+func (t TLSConfig) validate() []error {
+	if !t.Enabled {
+		return nil
+	}
+
+	var errs []error
+	if t.CertFile == "" || t.KeyFile == "" {
+		errs = append(errs, errors.New("listen.tls: cert_file and key_file are required"))
+	}
+	if _, err := TLSVersion(t.MinVersion); err != nil {
+		errs = append(errs, fmt.Errorf("listen.tls.min_version: %w", err))
+	}
+	if _, err := TLSClientAuth(t.ClientAuth); err != nil {
+		errs = append(errs, fmt.Errorf("listen.tls.client_auth: %w", err))
+	}
+	if _, err := TLSCipherSuites(t.CipherSuites); err != nil {
+		errs = append(errs, fmt.Errorf("listen.tls.cipher_suites: %w", err))
+	}
+	return errs
+}
+
+func (j JWTConfig) validate() []error {
+	if len(j.Keys) == 0 {
+		return nil
+	}
+
+	var errs []error
+	if j.TTL <= 0 {
+		errs = append(errs, errors.New("jwt.ttl: must be positive"))
+	}
+
+	ids := make(map[string]bool)
+	for i, k := range j.Keys {
+		if k.ID == "" {
+			errs = append(errs, fmt.Errorf("jwt.keys[%d].id: is required", i))
+		}
+		if ids[k.ID] {
+			errs = append(errs, fmt.Errorf("jwt.keys[%d].id: duplicate id %q", i, k.ID))
+		}
+		ids[k.ID] = true
+
+		switch k.Algorithm {
+		case "HS256":
+			if k.Secret == "" {
+				errs = append(errs, fmt.Errorf("jwt.keys[%d]: HS256 requires secret or secret_file", i))
+			}
+		case "EdDSA", "RS256":
+			if k.PrivateKeyFile == "" && k.PublicKeyFile == "" {
+				errs = append(errs, fmt.Errorf("jwt.keys[%d]: %s requires private_key_file or public_key_file", i, k.Algorithm))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("jwt.keys[%d].algorithm: unknown algorithm %q, expected HS256, EdDSA or RS256", i, k.Algorithm))
+		}
+	}
+
+	if !ids[j.SigningKeyID] {
+		errs = append(errs, fmt.Errorf("jwt.signing_key_id: %q does not match any key", j.SigningKeyID))
+	}
+	for _, k := range j.Keys {
+		if k.ID == j.SigningKeyID && k.Algorithm != "HS256" && k.PrivateKeyFile == "" {
+			errs = append(errs, errors.New("jwt.signing_key_id: the signing key needs a private key"))
+		}
+	}
+
+	return errs
+}
+
+func (a APIConfig) validate() []error {
+	var errs []error
+	versions := make(map[string]bool)
+	for i, d := range a.Deprecated {
+		if d.Version == "" {
+			errs = append(errs, fmt.Errorf("api.deprecated_versions[%d].version: is required", i))
+		}
+		if versions[d.Version] {
+			errs = append(errs, fmt.Errorf("api.deprecated_versions[%d].version: duplicate version %q", i, d.Version))
+		}
+		versions[d.Version] = true
+		if d.Since.IsZero() {
+			errs = append(errs, fmt.Errorf("api.deprecated_versions[%d].since: is required", i))
+		}
+		if !d.Sunset.IsZero() && d.Sunset.Before(d.Since) {
+			errs = append(errs, fmt.Errorf("api.deprecated_versions[%d].sunset: is before since", i))
+		}
+	}
+	return errs
+}
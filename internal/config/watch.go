@@ -0,0 +1,161 @@
+package config
+
+import (
+	"awesome-clean-arch/pkg/logging"
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+)
+
+type Section string
+
+// Reloadable sections are applied on the fly, changes to any other
+// section are ignored until the next restart.
+const (
+	SectionLog       Section = "log"
+	SectionCORS      Section = "cors"
+	SectionRateLimit Section = "rate_limit"
+	SectionFeatures  Section = "features"
+)
+
+type section struct {
+	name       Section
+	reloadable bool
+	get        func(c *Config) interface{}
+	restore    func(dst, src *Config)
+}
+
+var sections = []section{
+	{"is_debug", false, func(c *Config) interface{} { return c.IsDebug }, func(dst, src *Config) { dst.IsDebug = src.IsDebug }},
+	{"listen", false, func(c *Config) interface{} { return c.Listen }, func(dst, src *Config) { dst.Listen = src.Listen }},
+	{"http", false, func(c *Config) interface{} { return c.HTTP }, func(dst, src *Config) { dst.HTTP = src.HTTP }},
+	{"storage", false, func(c *Config) interface{} { return c.Storage }, func(dst, src *Config) { dst.Storage = src.Storage }},
+	{"jwt", false, func(c *Config) interface{} { return c.JWT }, func(dst, src *Config) { dst.JWT = src.JWT }},
+	{"request_signing", false, func(c *Config) interface{} { return c.Signing }, func(dst, src *Config) { dst.Signing = src.Signing }},
+	{"soft_delete", false, func(c *Config) interface{} { return c.Deletion }, func(dst, src *Config) { dst.Deletion = src.Deletion }},
+	{"idempotency", false, func(c *Config) interface{} { return c.Idempotency }, func(dst, src *Config) { dst.Idempotency = src.Idempotency }},
+	{"api", false, func(c *Config) interface{} { return c.API }, func(dst, src *Config) { dst.API = src.API }},
+	{"grpc", false, func(c *Config) interface{} { return c.GRPC }, func(dst, src *Config) { dst.GRPC = src.GRPC }},
+	{"graphql", false, func(c *Config) interface{} { return c.GraphQL }, func(dst, src *Config) { dst.GraphQL = src.GraphQL }},
+	{"events", false, func(c *Config) interface{} { return c.Events }, func(dst, src *Config) { dst.Events = src.Events }},
+	{"webhooks", false, func(c *Config) interface{} { return c.Webhooks }, func(dst, src *Config) { dst.Webhooks = src.Webhooks }},
+	{"outbox", false, func(c *Config) interface{} { return c.Outbox }, func(dst, src *Config) { dst.Outbox = src.Outbox }},
+	{"secrets", false, func(c *Config) interface{} { return c.Secrets }, func(dst, src *Config) { dst.Secrets = src.Secrets }},
+	{SectionLog, true, func(c *Config) interface{} { return c.Log }, nil},
+	{SectionCORS, true, func(c *Config) interface{} { return c.CORS }, nil},
+	{SectionRateLimit, true, func(c *Config) interface{} { return c.RateLimit }, nil},
+	{SectionFeatures, true, func(c *Config) interface{} { return c.Features }, nil},
+}
+
+type ChangeFunc func(cfg *Config)
+
+var subscribersMu sync.Mutex
+var subscribers = make(map[Section][]ChangeFunc)
+var reloadMu sync.Mutex
+
+// Subscribe registers fn to be called with the new configuration every time
+// the given reloadable section changes.
+func Subscribe(s Section, fn ChangeFunc) {
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+	subscribers[s] = append(subscribers[s], fn)
+}
+
+// Watch reloads the configuration on SIGHUP and whenever the config file
+// modification time changes, until ctx is cancelled.
+func Watch(ctx context.Context, interval time.Duration) {
+	logger := logging.GetLogger()
+	GetConfig()
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(hup)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		modTime := fileModTime(loader.Path())
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				logger.Info("SIGHUP received, reloading configuration")
+			case <-ticker.C:
+				mt := fileModTime(loader.Path())
+				if mt.Equal(modTime) {
+					continue
+				}
+				modTime = mt
+				logger.Info("Configuration file changed, reloading")
+			}
+
+			if err := Reload(); err != nil {
+				logger.Errorf("configuration reload failed, keeping current configuration: %s", err)
+			}
+		}
+	}()
+}
+
+// Reload re-reads the configuration and notifies subscribers of the
+// reloadable sections that changed.
+func Reload() error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	logger := logging.GetLogger()
+	current := GetConfig()
+	if loader == nil {
+		return errors.New("configuration is not loaded")
+	}
+
+	next, err := loader.Load()
+	if err != nil {
+		return err
+	}
+	if err = next.Validate(); err != nil {
+		return err
+	}
+
+	var changed []Section
+	for _, s := range sections {
+		if reflect.DeepEqual(s.get(current), s.get(next)) {
+			continue
+		}
+		if !s.reloadable {
+			logger.Warnf("configuration section %q changed, restart required to apply", s.name)
+			s.restore(next, current)
+			continue
+		}
+		changed = append(changed, s.name)
+	}
+
+	instance.Store(next)
+
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+	for _, s := range changed {
+		logger.Infof("configuration section %q reloaded", s)
+		for _, fn := range subscribers[s] {
+			fn(next)
+		}
+	}
+
+	return nil
+}
+
+func fileModTime(path string) time.Time {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return fi.ModTime()
+}
@@ -0,0 +1,55 @@
+package domainevents
+
+import (
+	"awesome-clean-arch/internal/profile"
+	"awesome-clean-arch/internal/user"
+	"awesome-clean-arch/internal/user_data"
+)
+
+// The events below are published on the bus by the outbox relay once a write
+// is committed, see NewSink. Created and updated events carry the state after
+// the change, the version of an update is the one it stored.
+
+type UserCreated struct {
+	User user.User
+}
+
+type UserUpdated struct {
+	User user.User
+}
+
+type UserDeleted struct {
+	ID string
+}
+
+type UserRestored struct {
+	ID string
+}
+
+type ProfileCreated struct {
+	Profile profile.Profile
+}
+
+type ProfileUpdated struct {
+	Profile profile.Profile
+}
+
+type ProfileDeleted struct {
+	UserID string
+}
+
+type ProfileRestored struct {
+	UserID string
+}
+
+type UserDataCreated struct {
+	UserData user_data.UserData
+}
+
+type UserDataUpdated struct {
+	UserData user_data.UserData
+}
+
+type UserDataDeleted struct {
+	UserID string
+}
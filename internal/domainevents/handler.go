@@ -0,0 +1,49 @@
+package domainevents
+
+import (
+	"awesome-clean-arch/internal/auth"
+	"awesome-clean-arch/internal/handlers"
+	"awesome-clean-arch/pkg/eventbus"
+	"awesome-clean-arch/pkg/logging"
+	"encoding/json"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+)
+
+const statsURL = "/eventbus/stats"
+
+var _ handlers.Handler = &handler{}
+
+type handler struct {
+	logger *logging.Logger
+	bus    *eventbus.Bus
+}
+
+func NewHandler(logger *logging.Logger, bus *eventbus.Bus) handlers.Handler {
+	return &handler{
+		logger: logger,
+		bus:    bus,
+	}
+}
+
+func (h *handler) Middleware() []handlers.Middleware {
+	return []handlers.Middleware{auth.RequireScope(auth.ScopeAuthAdmin)}
+}
+
+func (h *handler) Register(router handlers.Router) {
+	router.GET(statsURL, h.GetStats)
+}
+
+func (h *handler) Docs() []handlers.Doc {
+	return []handlers.Doc{
+		{Method: http.MethodGet, Path: statsURL, Summary: "List the calls, failures and latency of the event bus subscribers",
+			Tags: []string{"events"}, Scope: auth.ScopeAuthAdmin, Response: []eventbus.Stats{},
+			Description: "Latencies are in nanoseconds, the average is total_latency_ns divided by calls."},
+	}
+}
+
+func (h *handler) GetStats(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.bus.Stats())
+}
@@ -0,0 +1,54 @@
+package domainevents
+
+import (
+	"awesome-clean-arch/internal/outbox"
+	"awesome-clean-arch/internal/profile"
+	"awesome-clean-arch/internal/user"
+	"awesome-clean-arch/internal/user_data"
+	"awesome-clean-arch/pkg/eventbus"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// NewSink publishes the event of every outbox message on bus. A synchronous
+// subscriber that fails makes the relay publish the message again, so
+// subscribers must tolerate seeing an event more than once.
+func NewSink(bus *eventbus.Bus) outbox.Sink {
+	return outbox.SinkFunc(func(ctx context.Context, m outbox.Message) error {
+		switch m.Type {
+		case outbox.AggregateUser + "." + outbox.ActionCreate:
+			return publish(ctx, bus, m, func(u user.User) UserCreated { return UserCreated{User: u} })
+		case outbox.AggregateUser + "." + outbox.ActionUpdate:
+			return publish(ctx, bus, m, func(u user.User) UserUpdated { return UserUpdated{User: u} })
+		case outbox.AggregateUser + "." + outbox.ActionDelete:
+			return eventbus.Publish(ctx, bus, UserDeleted{ID: m.AggregateID})
+		case outbox.AggregateUser + "." + outbox.ActionRestore:
+			return eventbus.Publish(ctx, bus, UserRestored{ID: m.AggregateID})
+		case outbox.AggregateProfile + "." + outbox.ActionCreate:
+			return publish(ctx, bus, m, func(p profile.Profile) ProfileCreated { return ProfileCreated{Profile: p} })
+		case outbox.AggregateProfile + "." + outbox.ActionUpdate:
+			return publish(ctx, bus, m, func(p profile.Profile) ProfileUpdated { return ProfileUpdated{Profile: p} })
+		case outbox.AggregateProfile + "." + outbox.ActionDelete:
+			return eventbus.Publish(ctx, bus, ProfileDeleted{UserID: m.AggregateID})
+		case outbox.AggregateProfile + "." + outbox.ActionRestore:
+			return eventbus.Publish(ctx, bus, ProfileRestored{UserID: m.AggregateID})
+		case outbox.AggregateUserData + "." + outbox.ActionCreate:
+			return publish(ctx, bus, m, func(ud user_data.UserData) UserDataCreated { return UserDataCreated{UserData: ud} })
+		case outbox.AggregateUserData + "." + outbox.ActionUpdate:
+			return publish(ctx, bus, m, func(ud user_data.UserData) UserDataUpdated { return UserDataUpdated{UserData: ud} })
+		case outbox.AggregateUserData + "." + outbox.ActionDelete:
+			return eventbus.Publish(ctx, bus, UserDataDeleted{UserID: m.AggregateID})
+		}
+		return nil
+	})
+}
+
+// publish decodes the payload of m and publishes the event made of it.
+func publish[P, E any](ctx context.Context, bus *eventbus.Bus, m outbox.Message, event func(P) E) error {
+	var payload P
+	if err := json.Unmarshal(m.Payload, &payload); err != nil {
+		return fmt.Errorf("decode %s payload: %w", m.Type, err)
+	}
+	return eventbus.Publish(ctx, bus, event(payload))
+}
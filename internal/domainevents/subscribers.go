@@ -0,0 +1,37 @@
+package domainevents
+
+import (
+	"awesome-clean-arch/internal/auth"
+	"awesome-clean-arch/pkg/eventbus"
+	"context"
+	"strconv"
+	"time"
+)
+
+// Subscribe registers the reactions of the domains to each other's events.
+func Subscribe(bus *eventbus.Bus, keys auth.Repository) {
+	// Synchronous, so a failure has the outbox relay publish the deletion
+	// again until the keys are revoked.
+	eventbus.Subscribe(bus, "revoke API keys of deleted user", RevokeKeys(keys))
+}
+
+// RevokeKeys revokes the API keys of a deleted user that are not revoked yet.
+func RevokeKeys(keys auth.Repository) eventbus.Handler[UserDeleted] {
+	return func(ctx context.Context, e UserDeleted) error {
+		all, err := keys.FindByUserID(ctx, e.ID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, a := range all {
+			if a.RevokedAt != nil {
+				continue
+			}
+			if err = keys.Revoke(ctx, strconv.Itoa(a.ID), now); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,99 @@
+package events
+
+import (
+	"awesome-clean-arch/internal/outbox"
+	"awesome-clean-arch/pkg/logging"
+	"context"
+	"time"
+)
+
+// FeedOptions tune a Feed.
+type FeedOptions struct {
+	PollInterval time.Duration
+	BatchSize    int
+	// GapTimeout is how long a missing message ID is waited for, it may be
+	// added by a transaction that is not committed yet.
+	GapTimeout time.Duration
+}
+
+// Feed publishes the messages committed to the outbox to a Log, in the order
+// of their IDs. Every instance runs one, so its subscribers see the changes
+// made through any instance.
+type Feed struct {
+	repository outbox.Repository
+	log        *Log
+	options    FeedOptions
+	logger     *logging.Logger
+	cursor     int64
+}
+
+func NewFeed(repository outbox.Repository, log *Log, options FeedOptions, logger *logging.Logger) *Feed {
+	return &Feed{
+		repository: repository,
+		log:        log,
+		options:    options,
+		logger:     logger,
+	}
+}
+
+// Run reads the new messages every poll interval until ctx is done. It starts
+// with about as many of the last messages as the log keeps, so subscribers
+// resume across restarts.
+func (f *Feed) Run(ctx context.Context) {
+	ticker := time.NewTicker(f.options.PollInterval)
+	defer ticker.Stop()
+
+	begun := false
+	for {
+		if !begun {
+			begun = f.begin(ctx)
+		}
+		if begun {
+			f.read(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (f *Feed) begin(ctx context.Context) bool {
+	last, err := f.repository.LastID(ctx)
+	if err != nil {
+		f.logger.Errorf("events: failed to find the last outbox message: %s", err)
+		return false
+	}
+
+	f.cursor = last - int64(len(f.log.events))
+	if f.cursor < 0 {
+		f.cursor = 0
+	}
+	f.log.Begin(uint64(f.cursor))
+	return true
+}
+
+func (f *Feed) read(ctx context.Context) {
+	for ctx.Err() == nil {
+		messages, err := f.repository.FindAfter(ctx, f.cursor, f.options.BatchSize)
+		if err != nil {
+			return
+		}
+
+		for _, m := range messages {
+			// Messages are not committed in the order of their IDs, a missing
+			// one is given up on once the message after it is old enough.
+			if f.cursor > 0 && m.ID != f.cursor+1 && time.Since(m.CreatedAt) < f.options.GapTimeout {
+				return
+			}
+			f.log.Publish(FromMessage(m))
+			f.cursor = m.ID
+		}
+
+		if len(messages) < f.options.BatchSize {
+			return
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package events
+
+import (
+	"awesome-clean-arch/internal/auth"
+	"awesome-clean-arch/internal/handlers"
+	"awesome-clean-arch/pkg/logging"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	eventsURL   = "/events"
+	eventsWSURL = "/events/ws"
+)
+
+// resetType tells a resuming client that events were lost and its state
+// has to be reloaded.
+const resetType = "reset"
+
+var _ handlers.Handler = &handler{}
+
+type handler struct {
+	logger    *logging.Logger
+	log       *Log
+	heartbeat time.Duration
+	upgrader  websocket.Upgrader
+}
+
+// NewHandler streams the events of log. heartbeat is the interval of the
+// keep-alive comments and pings sent to idle subscribers.
+func NewHandler(logger *logging.Logger, log *Log, heartbeat time.Duration) handlers.Handler {
+	return &handler{
+		logger:    logger,
+		log:       log,
+		heartbeat: heartbeat,
+	}
+}
+
+func (h *handler) Middleware() []handlers.Middleware {
+	return nil
+}
+
+func (h *handler) Register(router handlers.Router) {
+	// Streams are long-lived by design.
+	streams := router.With(handlers.NoTimeout)
+	streams.GET(eventsURL, h.GetEvents)
+	streams.GET(eventsWSURL, h.GetEventsWS)
+}
+
+func (h *handler) Docs() []handlers.Doc {
+	query := []handlers.Param{
+		{Name: "types", Description: "Comma separated entity types: user, profile, user_data"},
+		{Name: "id", Description: "Only events of this entity id"},
+		{Name: "last_event_id", Description: "Resume after this event, like the Last-Event-ID header"},
+	}
+	description := "Events of the entity types the API key may read. A resumed stream starts with a " +
+		"reset event when the events after the given id are no longer available."
+	return []handlers.Doc{
+		{Method: http.MethodGet, Path: eventsURL, Summary: "Stream changes as Server-Sent Events", Description: description,
+			Tags: []string{"events"}, Query: query, Response: Event{}},
+		{Method: http.MethodGet, Path: eventsWSURL, Summary: "Stream changes over a WebSocket", Description: description,
+			Tags: []string{"events"}, Query: query, Response: Event{}, Status: http.StatusSwitchingProtocols},
+	}
+}
+
+// GetEvents streams events as text/event-stream. Every event carries its id,
+// so EventSource clients resume with Last-Event-ID after a reconnect.
+func (h *handler) GetEvents(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	if !handlers.IsStream(r) {
+		handlers.WriteError(w, http.StatusNotAcceptable, "Accept: text/event-stream required")
+		return
+	}
+	sub, complete, status, err := h.subscribe(r, r.Header.Get("Last-Event-ID"))
+	if err != nil {
+		handlers.WriteError(w, status, err.Error())
+		return
+	}
+	defer sub.Close()
+
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", (3 * time.Second).Milliseconds())
+	if !complete {
+		fmt.Fprintf(w, "event: %s\ndata: {\"type\":%q}\n\n", resetType, resetType)
+	}
+	rc.Flush()
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+	expired := expiry(r)
+
+	for {
+		select {
+		case e, ok := <-sub.C():
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				h.logger.Error(err)
+				continue
+			}
+			fmt.Fprintf(w, "id: %d\ndata: %s\n\n", e.ID, data)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case <-expired:
+			return
+		case <-r.Context().Done():
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// GetEventsWS sends every event as a JSON text message. Messages from the
+// client are ignored, it resumes with the last_event_id query parameter.
+func (h *handler) GetEventsWS(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	sub, complete, status, err := h.subscribe(r, "")
+	if err != nil {
+		handlers.WriteError(w, status, err.Error())
+		return
+	}
+	defer sub.Close()
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		conn.SetReadLimit(512)
+		conn.SetReadDeadline(time.Now().Add(2 * h.heartbeat))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(2 * h.heartbeat))
+		})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	write := func(v interface{}) error {
+		conn.SetWriteDeadline(time.Now().Add(h.heartbeat))
+		return conn.WriteJSON(v)
+	}
+	if !complete {
+		if err := write(map[string]string{"type": resetType}); err != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+	expired := expiry(r)
+
+	for {
+		select {
+		case e, ok := <-sub.C():
+			if !ok {
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseGoingAway, "stream ended, resume with last_event_id"),
+					time.Now().Add(time.Second))
+				return
+			}
+			if err := write(e); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(h.heartbeat)); err != nil {
+				return
+			}
+		case <-expired:
+			conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.ClosePolicyViolation, auth.ErrKeyExpired.Error()),
+				time.Now().Add(time.Second))
+			return
+		case <-done:
+			return
+		}
+	}
+}
+
+// subscribe authorizes the subscriber and subscribes it to the entity types
+// its key may read, narrowed by the types and id query parameters.
+func (h *handler) subscribe(r *http.Request, lastEventID string) (*Subscription, bool, int, error) {
+	a, ok := auth.FromContext(r.Context())
+	if !ok {
+		return nil, false, http.StatusUnauthorized, errors.New("API key required")
+	}
+
+	allowed := make(map[string]bool)
+	for entityType, scope := range scopes {
+		if a.HasScope(scope) {
+			allowed[entityType] = true
+		}
+	}
+
+	query := r.URL.Query()
+	filter := Filter{EntityID: query.Get("id")}
+	if types := query.Get("types"); types != "" {
+		for _, t := range strings.Split(types, ",") {
+			t = strings.TrimSpace(t)
+			scope, ok := scopes[t]
+			if !ok {
+				return nil, false, http.StatusBadRequest, fmt.Errorf("unknown entity type %q", t)
+			}
+			if !allowed[t] {
+				return nil, false, http.StatusForbidden, errors.New("missing scope " + scope)
+			}
+			filter.EntityTypes = append(filter.EntityTypes, t)
+		}
+	}
+	if len(allowed) == 0 {
+		var required []string
+		for _, scope := range scopes {
+			required = append(required, scope)
+		}
+		sort.Strings(required)
+		return nil, false, http.StatusForbidden, errors.New("missing scope, one of " + strings.Join(required, ", ") + " is required")
+	}
+
+	if lastEventID == "" {
+		lastEventID = query.Get("last_event_id")
+	}
+	var lastID uint64
+	if lastEventID != "" {
+		var err error
+		if lastID, err = strconv.ParseUint(lastEventID, 10, 64); err != nil {
+			return nil, false, http.StatusBadRequest, errors.New("invalid last event id")
+		}
+	}
+
+	sub, complete := h.log.Subscribe(lastID, func(e Event) bool {
+		return allowed[e.EntityType] && filter.Match(e)
+	})
+	return sub, complete, http.StatusOK, nil
+}
+
+// expiry fires when the key of the subscriber expires, a nil channel never
+// fires.
+func expiry(r *http.Request) <-chan time.Time {
+	a, _ := auth.FromContext(r.Context())
+	if a.ExpiresAt == nil {
+		return nil
+	}
+	return time.After(time.Until(*a.ExpiresAt))
+}
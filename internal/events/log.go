@@ -0,0 +1,161 @@
+package events
+
+import "sync"
+
+// subscriptionBuffer is the number of events a subscriber may lag behind
+// before it is disconnected. It resumes from the log when it reconnects.
+const subscriptionBuffer = 64
+
+// Log keeps the last events in memory and fans them out to subscribers.
+// Events keep the IDs of their outbox messages and are published in their
+// order, so a subscriber resumes from an ID on any instance and after a
+// restart.
+type Log struct {
+	mu     sync.Mutex
+	events []Event
+	next   int
+	full   bool
+	// The log holds every event published after floor up to lastID, once it
+	// has begun.
+	begun       bool
+	floor       uint64
+	lastID      uint64
+	subscribers map[*Subscription]struct{}
+	closed      bool
+}
+
+func NewLog(size int) *Log {
+	return &Log{
+		events:      make([]Event, size),
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Begin tells the log that the events published next follow afterID. Until
+// then, subscribers resuming from an ID are told they missed events.
+func (l *Log) Begin(afterID uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.begun, l.floor, l.lastID = true, afterID, afterID
+}
+
+// Publish stores e and delivers it to the subscribers it matches. An event
+// that is not after the last one is ignored.
+func (l *Log) Publish(e Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if e.ID <= l.lastID {
+		return
+	}
+	l.lastID = e.ID
+	if l.full {
+		l.floor = l.events[l.next].ID
+	}
+	l.events[l.next] = e
+	l.next = (l.next + 1) % len(l.events)
+	if l.next == 0 {
+		l.full = true
+	}
+
+	for s := range l.subscribers {
+		if e.ID <= s.after || !s.match(e) {
+			continue
+		}
+		select {
+		case s.c <- e:
+		default:
+			l.remove(s)
+		}
+	}
+}
+
+// Subscribe returns a subscription to the events accepted by match. With a
+// lastID, the retained events after it are delivered first; complete is
+// false when some of them are no longer retained, or lastID is unknown.
+func (l *Log) Subscribe(lastID uint64, match func(Event) bool) (s *Subscription, complete bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var missed []Event
+	complete = true
+	if lastID != 0 {
+		missed, complete = l.since(lastID, match)
+	}
+
+	s = &Subscription{c: make(chan Event, len(missed)+subscriptionBuffer), match: match, after: lastID, log: l}
+	for _, e := range missed {
+		s.c <- e
+	}
+	if l.closed {
+		close(s.c)
+		return s, complete
+	}
+	l.subscribers[s] = struct{}{}
+	return s, complete
+}
+
+// since lists the retained events after lastID accepted by match. An ID this
+// instance has not reached yet, e.g. handed out by a quicker one, misses
+// nothing, its subscription skips the events up to it.
+func (l *Log) since(lastID uint64, match func(Event) bool) ([]Event, bool) {
+	switch {
+	case !l.begun || lastID < l.floor:
+		return nil, false
+	case lastID >= l.lastID:
+		return nil, true
+	}
+
+	var missed []Event
+	for _, e := range l.retained() {
+		if e.ID > lastID && match(e) {
+			missed = append(missed, e)
+		}
+	}
+	return missed, true
+}
+
+// retained lists the stored events, oldest first.
+func (l *Log) retained() []Event {
+	if !l.full {
+		return append([]Event(nil), l.events[:l.next]...)
+	}
+	return append(append([]Event(nil), l.events[l.next:]...), l.events[:l.next]...)
+}
+
+// Close ends every subscription, streams return when the server shuts down.
+func (l *Log) Close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = true
+	for s := range l.subscribers {
+		l.remove(s)
+	}
+}
+
+func (l *Log) remove(s *Subscription) {
+	if _, ok := l.subscribers[s]; ok {
+		delete(l.subscribers, s)
+		close(s.c)
+	}
+}
+
+type Subscription struct {
+	c     chan Event
+	match func(Event) bool
+	// after is the ID the subscriber resumed from.
+	after uint64
+	log   *Log
+}
+
+// C delivers the events, it is closed when the subscriber fell behind or the
+// log was closed.
+func (s *Subscription) C() <-chan Event {
+	return s.c
+}
+
+func (s *Subscription) Close() {
+	s.log.mu.Lock()
+	defer s.log.mu.Unlock()
+	s.log.remove(s)
+}
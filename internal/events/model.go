@@ -0,0 +1,64 @@
+package events
+
+import (
+	"awesome-clean-arch/internal/auth"
+	"awesome-clean-arch/internal/outbox"
+	"encoding/json"
+	"time"
+)
+
+// Event is a change of a user, profile or user_data. Data is the state of
+// the entity after the change, empty when it is no longer readable.
+type Event struct {
+	ID         uint64          `json:"id"`
+	Type       string          `json:"type"`
+	EntityType string          `json:"entity_type"`
+	EntityID   string          `json:"entity_id"`
+	Action     string          `json:"action"`
+	Data       json.RawMessage `json:"data,omitempty"`
+	RequestID  string          `json:"request_id,omitempty"`
+	Time       time.Time       `json:"time"`
+}
+
+// scopes are the entity types of the feed and the scope needed to see them.
+var scopes = map[string]string{
+	outbox.AggregateUser:     auth.ScopeUsersRead,
+	outbox.AggregateProfile:  auth.ScopeProfilesRead,
+	outbox.AggregateUserData: auth.ScopeUserDataRead,
+}
+
+// FromMessage converts a message of the outbox. The event has the id of the
+// message.
+func FromMessage(m outbox.Message) Event {
+	return Event{
+		ID:         uint64(m.ID),
+		Type:       m.Type,
+		EntityType: m.AggregateType,
+		EntityID:   m.AggregateID,
+		Action:     m.Action,
+		Data:       m.Payload,
+		RequestID:  m.RequestID,
+		Time:       m.CreatedAt,
+	}
+}
+
+// Filter selects the events of a subscriber, zero values match everything.
+type Filter struct {
+	EntityTypes []string
+	EntityID    string
+}
+
+func (f Filter) Match(e Event) bool {
+	if f.EntityID != "" && f.EntityID != e.EntityID {
+		return false
+	}
+	if len(f.EntityTypes) == 0 {
+		return true
+	}
+	for _, t := range f.EntityTypes {
+		if t == e.EntityType {
+			return true
+		}
+	}
+	return false
+}
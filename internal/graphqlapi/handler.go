@@ -0,0 +1,137 @@
+package graphqlapi
+
+import (
+	"awesome-clean-arch/internal/handlers"
+	"awesome-clean-arch/pkg/logging"
+	"encoding/json"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+)
+
+const graphqlURL = "/graphql"
+
+var _ handlers.Handler = &handler{}
+
+type handler struct {
+	logger *logging.Logger
+	repos  Repositories
+	schema graphql.Schema
+	limits Limits
+}
+
+// Request is a GraphQL request, sent as JSON body of a POST or as query
+// parameters of a GET with variables encoded as JSON.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+func NewHandler(logger *logging.Logger, repos Repositories, limits Limits) handlers.Handler {
+	schema, err := NewSchema(repos)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	return &handler{
+		logger: logger,
+		repos:  repos,
+		schema: schema,
+		limits: limits,
+	}
+}
+
+func (h *handler) Middleware() []handlers.Middleware {
+	return nil
+}
+
+func (h *handler) Register(router handlers.Router) {
+	router.GET(graphqlURL, h.GetQuery)
+	router.POST(graphqlURL, h.PostQuery)
+}
+
+func (h *handler) Docs() []handlers.Doc {
+	description := "Fields are authorized with the scopes of the matching REST routes. " +
+		"Queries exceeding the depth or complexity limits are rejected with 400."
+	return []handlers.Doc{
+		{Method: http.MethodGet, Path: graphqlURL, Summary: "Run a GraphQL query", Description: description,
+			Tags: []string{"graphql"}, Response: graphql.Result{}, Query: []handlers.Param{
+				{Name: "query", Required: true},
+				{Name: "operationName"},
+				{Name: "variables", Description: "JSON object"},
+			}},
+		{Method: http.MethodPost, Path: graphqlURL, Summary: "Run a GraphQL query", Description: description,
+			Tags: []string{"graphql"}, Request: Request{}, Response: graphql.Result{}},
+	}
+}
+
+func (h *handler) GetQuery(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	query := r.URL.Query()
+	req := Request{
+		Query:         query.Get("query"),
+		OperationName: query.Get("operationName"),
+	}
+	if v := query.Get("variables"); v != "" {
+		if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+			h.writeErrors(w, http.StatusBadRequest, gqlerrors.NewFormattedError("invalid variables: "+err.Error()))
+			return
+		}
+	}
+	h.execute(w, r, req)
+}
+
+func (h *handler) PostQuery(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	var req Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrors(w, http.StatusBadRequest, gqlerrors.NewFormattedError("invalid JSON: "+err.Error()))
+		return
+	}
+	h.execute(w, r, req)
+}
+
+// execute parses and validates the query and checks its limits before any
+// resolver runs. Errors of resolvers are reported next to the data with 200.
+func (h *handler) execute(w http.ResponseWriter, r *http.Request, req Request) {
+	if req.Query == "" {
+		h.writeErrors(w, http.StatusBadRequest, gqlerrors.NewFormattedError("query is required"))
+		return
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{
+		Body: []byte(req.Query),
+		Name: "GraphQL request",
+	})})
+	if err != nil {
+		h.writeErrors(w, http.StatusBadRequest, gqlerrors.FormatError(err))
+		return
+	}
+	if result := graphql.ValidateDocument(&h.schema, doc, nil); !result.IsValid {
+		h.writeErrors(w, http.StatusBadRequest, result.Errors...)
+		return
+	}
+	if err := h.limits.check(h.schema, doc, req.Variables); err != nil {
+		h.writeErrors(w, http.StatusBadRequest, gqlerrors.NewFormattedError(err.Error()))
+		return
+	}
+
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        h.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       withLoaders(r.Context(), newLoaders(h.repos)),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *handler) writeErrors(w http.ResponseWriter, status int, errs ...gqlerrors.FormattedError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(graphql.Result{Errors: errs})
+}
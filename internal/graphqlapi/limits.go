@@ -0,0 +1,168 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"strconv"
+	"strings"
+)
+
+// defaultListSize is the number of items a list field without a limit
+// argument is assumed to return.
+const defaultListSize = 10
+
+// Limits bound the cost of a query before it is executed. Zero disables a
+// limit.
+type Limits struct {
+	MaxDepth      int
+	MaxComplexity int
+}
+
+// cost measures an operation. Every field costs 1, the fields selected under
+// a list count once per expected item.
+type cost struct {
+	schema    graphql.Schema
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+}
+
+func (l Limits) check(schema graphql.Schema, doc *ast.Document, variables map[string]interface{}) error {
+	c := cost{
+		schema:    schema,
+		fragments: make(map[string]*ast.FragmentDefinition),
+		variables: variables,
+	}
+	var operations []*ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		switch def := def.(type) {
+		case *ast.FragmentDefinition:
+			c.fragments[def.Name.Value] = def
+		case *ast.OperationDefinition:
+			operations = append(operations, def)
+		}
+	}
+
+	for _, op := range operations {
+		var root *graphql.Object
+		switch op.Operation {
+		case ast.OperationTypeQuery:
+			root = schema.QueryType()
+		case ast.OperationTypeMutation:
+			root = schema.MutationType()
+		}
+		if root == nil {
+			continue
+		}
+
+		depth, complexity := c.selectionSet(root, op.SelectionSet, map[string]bool{})
+		if l.MaxDepth > 0 && depth > l.MaxDepth {
+			return fmt.Errorf("query depth %d exceeds the maximum of %d", depth, l.MaxDepth)
+		}
+		if l.MaxComplexity > 0 && complexity > l.MaxComplexity {
+			return fmt.Errorf("query complexity %d exceeds the maximum of %d", complexity, l.MaxComplexity)
+		}
+	}
+	return nil
+}
+
+// selectionSet returns the depth and complexity of set selected on parent.
+// spread holds the fragments being expanded, to stop on cycles.
+func (c cost) selectionSet(parent graphql.Type, set *ast.SelectionSet, spread map[string]bool) (int, int) {
+	if set == nil {
+		return 0, 0
+	}
+	var depth, complexity int
+	for _, s := range set.Selections {
+		var d, n int
+		switch s := s.(type) {
+		case *ast.Field:
+			d, n = c.field(parent, s, spread)
+		case *ast.InlineFragment:
+			d, n = c.selectionSet(c.condition(parent, s.TypeCondition), s.SelectionSet, spread)
+		case *ast.FragmentSpread:
+			f, ok := c.fragments[s.Name.Value]
+			if !ok || spread[s.Name.Value] {
+				continue
+			}
+			spread[s.Name.Value] = true
+			d, n = c.selectionSet(c.condition(parent, f.TypeCondition), f.SelectionSet, spread)
+			delete(spread, s.Name.Value)
+		}
+		if d > depth {
+			depth = d
+		}
+		complexity += n
+	}
+	return depth, complexity
+}
+
+func (c cost) field(parent graphql.Type, f *ast.Field, spread map[string]bool) (int, int) {
+	if strings.HasPrefix(f.Name.Value, "__") {
+		return 0, 0
+	}
+
+	var fieldType graphql.Type
+	if obj, ok := parent.(*graphql.Object); ok {
+		if def, ok := obj.Fields()[f.Name.Value]; ok {
+			fieldType = def.Type
+		}
+	}
+
+	list := false
+	for {
+		if nn, ok := fieldType.(*graphql.NonNull); ok {
+			fieldType = nn.OfType
+			continue
+		}
+		if l, ok := fieldType.(*graphql.List); ok {
+			list = true
+			fieldType = l.OfType
+			continue
+		}
+		break
+	}
+
+	depth, complexity := c.selectionSet(fieldType, f.SelectionSet, spread)
+	if list {
+		complexity *= c.listSize(f)
+	}
+	return depth + 1, complexity + 1
+}
+
+// listSize is the limit argument of f when it has one.
+func (c cost) listSize(f *ast.Field) int {
+	for _, arg := range f.Arguments {
+		if arg.Name.Value != "limit" {
+			continue
+		}
+		switch v := arg.Value.(type) {
+		case *ast.IntValue:
+			if n, err := strconv.Atoi(v.Value); err == nil && n >= 0 {
+				return n
+			}
+		case *ast.Variable:
+			switch n := c.variables[v.Name.Value].(type) {
+			case float64:
+				if n >= 0 {
+					return int(n)
+				}
+			case int:
+				if n >= 0 {
+					return n
+				}
+			}
+		}
+	}
+	return defaultListSize
+}
+
+func (c cost) condition(parent graphql.Type, named *ast.Named) graphql.Type {
+	if named == nil {
+		return parent
+	}
+	if t, ok := c.schema.TypeMap()[named.Name.Value]; ok {
+		return t
+	}
+	return parent
+}
@@ -0,0 +1,132 @@
+package graphqlapi
+
+import (
+	"awesome-clean-arch/internal/auth"
+	"awesome-clean-arch/internal/profile"
+	"awesome-clean-arch/internal/user"
+	"awesome-clean-arch/internal/user_data"
+	"context"
+	"strconv"
+	"sync"
+)
+
+// loader batches the keys asked for while one level of a query is resolved
+// and fetches them with a single call once the first value is needed. The
+// executor resolves a level breadth-first, so every sibling has registered
+// its key by then.
+type loader[V any] struct {
+	fetch func(ctx context.Context, keys []string) (map[string]V, error)
+
+	mu      sync.Mutex
+	pending []string
+	queued  map[string]bool
+	values  map[string]V
+	errs    map[string]error
+}
+
+func newLoader[V any](fetch func(ctx context.Context, keys []string) (map[string]V, error)) *loader[V] {
+	return &loader[V]{
+		fetch:  fetch,
+		queued: make(map[string]bool),
+		values: make(map[string]V),
+		errs:   make(map[string]error),
+	}
+}
+
+// load queues key and returns a thunk resolving to its value, or to nil when
+// the key was not found.
+func (l *loader[V]) load(ctx context.Context, key string) func() (interface{}, error) {
+	l.mu.Lock()
+	if !l.queued[key] {
+		l.queued[key] = true
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (interface{}, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if _, done := l.values[key]; !done && l.errs[key] == nil && len(l.pending) > 0 {
+			keys := l.pending
+			l.pending = nil
+			values, err := l.fetch(ctx, keys)
+			for _, k := range keys {
+				if err != nil {
+					l.errs[k] = err
+				} else if v, ok := values[k]; ok {
+					l.values[k] = v
+				}
+			}
+		}
+
+		if err := l.errs[key]; err != nil {
+			return nil, err
+		}
+		if v, ok := l.values[key]; ok {
+			return v, nil
+		}
+		return nil, nil
+	}
+}
+
+// loaders live for one request, so values are never served across callers.
+type loaders struct {
+	users    *loader[user.User]
+	profiles *loader[profile.Profile]
+	userData *loader[user_data.UserData]
+	apiKeys  *loader[[]auth.Auth]
+}
+
+type loadersKey struct{}
+
+func newLoaders(repos Repositories) *loaders {
+	return &loaders{
+		users: newLoader(func(ctx context.Context, ids []string) (map[string]user.User, error) {
+			all, err := repos.Users.FindByIDs(ctx, ids, user.FindOptions{})
+			m := make(map[string]user.User, len(all))
+			for _, u := range all {
+				m[strconv.Itoa(u.ID)] = u
+			}
+			return m, err
+		}),
+		profiles: newLoader(func(ctx context.Context, userIDs []string) (map[string]profile.Profile, error) {
+			all, err := repos.Profiles.FindByUserIDs(ctx, userIDs, profile.FindOptions{})
+			m := make(map[string]profile.Profile, len(all))
+			for _, p := range all {
+				m[p.ID] = p
+			}
+			return m, err
+		}),
+		userData: newLoader(func(ctx context.Context, userIDs []string) (map[string]user_data.UserData, error) {
+			all, err := repos.UserData.FindByUserIDs(ctx, userIDs)
+			m := make(map[string]user_data.UserData, len(all))
+			for _, ud := range all {
+				m[strconv.Itoa(ud.ID)] = ud
+			}
+			return m, err
+		}),
+		apiKeys: newLoader(func(ctx context.Context, userIDs []string) (map[string][]auth.Auth, error) {
+			all, err := repos.Auth.FindByUserIDs(ctx, userIDs)
+			m := make(map[string][]auth.Auth, len(userIDs))
+			for _, id := range userIDs {
+				m[id] = []auth.Auth{}
+			}
+			for _, a := range all {
+				if a.UserID != nil {
+					id := strconv.Itoa(*a.UserID)
+					m[id] = append(m[id], a.Masked())
+				}
+			}
+			return m, err
+		}),
+	}
+}
+
+func withLoaders(ctx context.Context, l *loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
@@ -0,0 +1,229 @@
+package graphqlapi
+
+import (
+	"awesome-clean-arch/internal/auth"
+	"awesome-clean-arch/internal/profile"
+	"awesome-clean-arch/internal/user"
+	"awesome-clean-arch/internal/user_data"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/graphql-go/graphql"
+	"strconv"
+)
+
+// Repositories back the resolvers, the same ones the REST handlers use.
+type Repositories struct {
+	Users    user.Repository
+	Profiles profile.Repository
+	UserData user_data.Repository
+	Auth     auth.Repository
+}
+
+// requireScope is checked by every resolver reading a repository, so a key
+// only sees the parts of the graph its scopes allow.
+func requireScope(ctx context.Context, scope string) error {
+	a, ok := auth.FromContext(ctx)
+	if !ok {
+		return errors.New("API key required")
+	}
+	if !a.HasScope(scope) {
+		return errors.New("missing scope " + scope)
+	}
+	return nil
+}
+
+// NewSchema builds the query schema:
+//
+//	user(id), users(includeDeleted, limit), profile(username), profiles(limit)
+//
+// Users expose their profile, userData and apiKeys, profiles their user and
+// userData.
+func NewSchema(repos Repositories) (graphql.Schema, error) {
+	var userType, profileType *graphql.Object
+
+	userDataType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "UserData",
+		Fields: graphql.Fields{
+			"userId": &graphql.Field{Type: graphql.NewNonNull(graphql.ID), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(user_data.UserData).ID, nil
+			}},
+			"school":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"version": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+
+	apiKeyType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "ApiKey",
+		Description: "An API key of a user, the key itself is masked.",
+		Fields: graphql.Fields{
+			"id":         &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"apiKey":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"scopes":     &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String)))},
+			"userId":     &graphql.Field{Type: graphql.ID},
+			"rateLimit":  &graphql.Field{Type: graphql.Float},
+			"rateBurst":  &graphql.Field{Type: graphql.Int},
+			"createdAt":  &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"expiresAt":  &graphql.Field{Type: graphql.DateTime},
+			"lastUsedAt": &graphql.Field{Type: graphql.DateTime},
+			"revokedAt":  &graphql.Field{Type: graphql.DateTime},
+		},
+	})
+
+	userType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":        &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+				"username":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"version":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"deletedAt": &graphql.Field{Type: graphql.DateTime},
+				"profile": &graphql.Field{Type: profileType, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if err := requireScope(p.Context, auth.ScopeProfilesRead); err != nil {
+						return nil, err
+					}
+					return loadersFrom(p.Context).profiles.load(p.Context, strconv.Itoa(p.Source.(user.User).ID)), nil
+				}},
+				"userData": &graphql.Field{Type: userDataType, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if err := requireScope(p.Context, auth.ScopeUserDataRead); err != nil {
+						return nil, err
+					}
+					return loadersFrom(p.Context).userData.load(p.Context, strconv.Itoa(p.Source.(user.User).ID)), nil
+				}},
+				"apiKeys": &graphql.Field{
+					Type:        graphql.NewList(graphql.NewNonNull(apiKeyType)),
+					Description: "Available to the owner of the keys and to admins.",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						u := p.Source.(user.User)
+						caller, ok := auth.FromContext(p.Context)
+						if !ok {
+							return nil, errors.New("API key required")
+						}
+						if !caller.HasScope(auth.ScopeAuthAdmin) && (caller.UserID == nil || *caller.UserID != u.ID) {
+							return nil, errors.New("not the owner of these keys")
+						}
+						return loadersFrom(p.Context).apiKeys.load(p.Context, strconv.Itoa(u.ID)), nil
+					},
+				},
+			}
+		}),
+	})
+
+	profileType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Profile",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"userId": &graphql.Field{Type: graphql.NewNonNull(graphql.ID), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(profile.Profile).ID, nil
+				}},
+				"username":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"firstname": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"lastname":  &graphql.Field{Type: graphql.String},
+				"phone":     &graphql.Field{Type: graphql.String},
+				"address":   &graphql.Field{Type: graphql.String},
+				"city":      &graphql.Field{Type: graphql.String},
+				"school":    &graphql.Field{Type: graphql.String},
+				"version":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"deletedAt": &graphql.Field{Type: graphql.DateTime},
+				"user": &graphql.Field{Type: userType, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if err := requireScope(p.Context, auth.ScopeUsersRead); err != nil {
+						return nil, err
+					}
+					return loadersFrom(p.Context).users.load(p.Context, p.Source.(profile.Profile).ID), nil
+				}},
+				"userData": &graphql.Field{Type: userDataType, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if err := requireScope(p.Context, auth.ScopeUserDataRead); err != nil {
+						return nil, err
+					}
+					return loadersFrom(p.Context).userData.load(p.Context, p.Source.(profile.Profile).ID), nil
+				}},
+			}
+		}),
+	})
+
+	limitArg := &graphql.ArgumentConfig{Type: graphql.Int, Description: "Return at most this many items"}
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"user": &graphql.Field{
+				Type: userType,
+				Args: graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)}},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if err := requireScope(p.Context, auth.ScopeUsersRead); err != nil {
+						return nil, err
+					}
+					u, err := repos.Users.FindOne(p.Context, p.Args["id"].(string), user.FindOptions{})
+					return orNotFound(u, err)
+				},
+			},
+			"users": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(userType))),
+				Args: graphql.FieldConfigArgument{
+					"includeDeleted": &graphql.ArgumentConfig{Type: graphql.Boolean, DefaultValue: false,
+						Description: "Also return soft-deleted users, admin only"},
+					"limit": limitArg,
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if err := requireScope(p.Context, auth.ScopeUsersRead); err != nil {
+						return nil, err
+					}
+					includeDeleted, _ := p.Args["includeDeleted"].(bool)
+					if includeDeleted && !auth.IsAdmin(p.Context) {
+						return nil, errors.New("includeDeleted requires scope " + auth.ScopeAuthAdmin)
+					}
+					all, err := repos.Users.FindAll(p.Context, user.FindOptions{IncludeDeleted: includeDeleted})
+					if err != nil {
+						return nil, err
+					}
+					return all[:limit(p.Args, len(all))], nil
+				},
+			},
+			"profile": &graphql.Field{
+				Type: profileType,
+				Args: graphql.FieldConfigArgument{"username": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)}},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if err := requireScope(p.Context, auth.ScopeProfilesRead); err != nil {
+						return nil, err
+					}
+					pr, err := repos.Profiles.FindOne(p.Context, p.Args["username"].(string), profile.FindOptions{})
+					return orNotFound(pr, err)
+				},
+			},
+			"profiles": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(profileType))),
+				Args: graphql.FieldConfigArgument{"limit": limitArg},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if err := requireScope(p.Context, auth.ScopeProfilesRead); err != nil {
+						return nil, err
+					}
+					all, err := repos.Profiles.FindAll(p.Context, profile.FindOptions{})
+					if err != nil {
+						return nil, err
+					}
+					return all[:limit(p.Args, len(all))], nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query})
+}
+
+// orNotFound resolves missing rows to null instead of an error.
+func orNotFound(v interface{}, err error) (interface{}, error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func limit(args map[string]interface{}, n int) int {
+	if l, ok := args["limit"].(int); ok && l >= 0 && l < n {
+		return l
+	}
+	return n
+}
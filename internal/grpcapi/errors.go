@@ -0,0 +1,53 @@
+package grpcapi
+
+import (
+	"awesome-clean-arch/pkg/validate"
+	"context"
+	"database/sql"
+	"errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Error converts an error returned by a repository into a status, notFound
+// is the message used for sql.ErrNoRows.
+func Error(err error, notFound string) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return status.Error(codes.NotFound, notFound)
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// ValidationError reports the failed fields of err, as returned by
+// validate.Struct, in a BadRequest detail.
+func ValidationError(err error) error {
+	st := status.New(codes.InvalidArgument, "validation failed")
+	br := &errdetails.BadRequest{}
+	for _, f := range validate.Fields(err) {
+		br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       f.Field,
+			Description: f.Message,
+		})
+	}
+	if detailed, derr := st.WithDetails(br); derr == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// VersionRequired is returned by updates that do not send the version they
+// read, the counterpart of 428 Precondition Required.
+func VersionRequired() error {
+	return status.Error(codes.FailedPrecondition, "version is required")
+}
+
+// Conflict is returned when the stored version differs from the one sent.
+func Conflict(err error) error {
+	return status.Error(codes.Aborted, err.Error())
+}
@@ -0,0 +1,112 @@
+package grpcapi
+
+import (
+	"awesome-clean-arch/internal/handlers"
+	"awesome-clean-arch/pkg/logging"
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// RequestIDMetadata is the metadata key carrying the request id, the gRPC
+// counterpart of the X-Request-ID header.
+var RequestIDMetadata = strings.ToLower(handlers.RequestIDHeader)
+
+// RequestID propagates the incoming request id or generates a new one, and
+// sends it back in the response header.
+func RequestID() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(withRequestID(ctx), req)
+	}
+}
+
+func StreamRequestID() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, WrapStream(ss, withRequestID(ss.Context())))
+	}
+}
+
+func withRequestID(ctx context.Context) context.Context {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(RequestIDMetadata); len(values) > 0 {
+			id = values[0]
+		}
+	}
+	if id == "" || len(id) > 128 {
+		id = handlers.NewRequestID()
+	}
+	grpc.SetHeader(ctx, metadata.Pairs(RequestIDMetadata, id))
+	return handlers.WithRequestID(ctx, id)
+}
+
+// Logging logs one line per call with status code and duration.
+func Logging(logger *logging.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logCall(logger, ctx, info.FullMethod, err, start)
+		return resp, err
+	}
+}
+
+func StreamLogging(logger *logging.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		logCall(logger, ss.Context(), info.FullMethod, err, start)
+		return err
+	}
+}
+
+func logCall(logger *logging.Logger, ctx context.Context, method string, err error, start time.Time) {
+	var addr string
+	if p, ok := peer.FromContext(ctx); ok {
+		addr = p.Addr.String()
+	}
+	logger.GetLoggerWithField("request_id", handlers.RequestIDFromContext(ctx)).
+		Infof("gRPC %s %s %s %s", method, status.Code(err), time.Since(start), addr)
+}
+
+// Recovery turns a panic in a method into an Internal status.
+func Recovery(logger *logging.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer recoverCall(logger, ctx, &err)
+		return handler(ctx, req)
+	}
+}
+
+func StreamRecovery(logger *logging.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer recoverCall(logger, ss.Context(), &err)
+		return handler(srv, ss)
+	}
+}
+
+func recoverCall(logger *logging.Logger, ctx context.Context, err *error) {
+	if rec := recover(); rec != nil {
+		logger.GetLoggerWithField("request_id", handlers.RequestIDFromContext(ctx)).
+			Errorf("panic: %v\n%s", rec, debug.Stack())
+		*err = status.Error(codes.Internal, "internal server error")
+	}
+}
+
+type wrappedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *wrappedStream) Context() context.Context {
+	return s.ctx
+}
+
+// WrapStream replaces the context of ss, for interceptors that enrich it.
+func WrapStream(ss grpc.ServerStream, ctx context.Context) grpc.ServerStream {
+	return &wrappedStream{ServerStream: ss, ctx: ctx}
+}
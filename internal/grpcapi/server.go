@@ -0,0 +1,55 @@
+package grpcapi
+
+import (
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// Service is the gRPC counterpart of a handlers.Handler.
+type Service interface {
+	Register(s *grpc.Server)
+	// Scopes maps the full names of the service methods to the scope they
+	// require. An empty scope only requires authentication, methods left
+	// out are public.
+	Scopes() map[string]string
+}
+
+// FullMethod returns the name gRPC gives to method of service.
+func FullMethod(service grpc.ServiceDesc, method string) string {
+	return "/" + service.ServiceName + "/" + method
+}
+
+// Scopes merges the scopes of services.
+func Scopes(services ...Service) map[string]string {
+	scopes := make(map[string]string)
+	for _, s := range services {
+		for method, scope := range s.Scopes() {
+			scopes[method] = scope
+		}
+	}
+	return scopes
+}
+
+// NewServer registers services next to the health service, and the
+// reflection service when reflect is set. Every service is reported as
+// serving until the returned health server is told otherwise.
+func NewServer(services []Service, reflect bool, opts ...grpc.ServerOption) (*grpc.Server, *health.Server) {
+	server := grpc.NewServer(opts...)
+
+	healthServer := health.NewServer()
+	grpc_health_v1.RegisterHealthServer(server, healthServer)
+
+	for _, s := range services {
+		s.Register(server)
+	}
+	for name := range server.GetServiceInfo() {
+		healthServer.SetServingStatus(name, grpc_health_v1.HealthCheckResponse_SERVING)
+	}
+
+	if reflect {
+		reflection.Register(server)
+	}
+	return server, healthServer
+}
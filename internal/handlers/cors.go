@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"awesome-clean-arch/internal/config"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// CORS answers preflight requests and sets cross-origin headers. Its settings
+// can be swapped at runtime with Update.
+type CORS struct {
+	cfg atomic.Pointer[config.CORSConfig]
+}
+
+func NewCORS(cfg config.CORSConfig) *CORS {
+	c := &CORS{}
+	c.Update(cfg)
+	return c
+}
+
+func (c *CORS) Update(cfg config.CORSConfig) {
+	c.cfg.Store(&cfg)
+}
+
+func (c *CORS) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := c.cfg.Load()
+		origin := r.Header.Get("Origin")
+
+		if origin == "" || !originAllowed(cfg.AllowedOrigins, origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join(cfg.AllowedMethods, ", "))
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(cfg.AllowedHeaders, ", "))
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(cfg.MaxAge))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func originAllowed(allowed []string, origin string) bool {
+	for _, o := range allowed {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
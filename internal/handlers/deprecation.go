@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"awesome-clean-arch/pkg/logging"
+	"github.com/julienschmidt/httprouter"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Deprecation announces that an API version will be removed. Since is sent
+// in the Deprecation header (RFC 9745), Sunset (RFC 8594) and Link are
+// optional.
+type Deprecation struct {
+	Version string
+	Since   time.Time
+	Sunset  time.Time
+	// Link points to the migration guide or the successor version.
+	Link string
+}
+
+type deprecation struct {
+	Deprecation
+	logger *logging.Logger
+}
+
+// Deprecate returns a group of router under prefix whose routes answer with
+// deprecation headers and log every call, so the remaining clients of the
+// version can be found before it is removed.
+func Deprecate(router Router, prefix string, d Deprecation, logger *logging.Logger, middleware ...Middleware) Router {
+	g := router.Group(prefix, middleware...).(*group)
+	g.deprecation = &deprecation{Deprecation: d, logger: logger}
+	return g
+}
+
+// middleware is added to every route of a deprecated group, the route is
+// logged as registered to keep the metric cardinality low.
+func (d *deprecation) middleware(method, path string) Middleware {
+	return func(next httprouter.Handle) httprouter.Handle {
+		return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+			h := w.Header()
+			h.Set("Deprecation", "@"+strconv.FormatInt(d.Since.Unix(), 10))
+			if !d.Sunset.IsZero() {
+				h.Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+			}
+			if d.Link != "" {
+				h.Add("Link", "<"+d.Link+`>; rel="deprecation"`)
+			}
+
+			d.logger.WithFields(logrus.Fields{
+				"metric":     "deprecated_api_call",
+				"version":    d.Version,
+				"route":      method + " " + path,
+				"request_id": RequestIDFromContext(r.Context()),
+				"client":     r.RemoteAddr,
+			}).Info("deprecated API version called")
+
+			next(w, r, params)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"sort"
+	"sync"
+)
+
+// Doc describes a route for the API specification. Request and Response
+// hold a value of the model sent and returned, Status defaults to 200.
+type Doc struct {
+	Method      string
+	Path        string
+	Summary     string
+	Description string
+	Tags        []string
+	Scope       string
+	Query       []Param
+	Request     interface{}
+	Response    interface{}
+	Status      int
+	// Conditional routes support ETag based conditional requests.
+	Conditional bool
+	// Idempotent POST routes accept an Idempotency-Key header.
+	Idempotent bool
+}
+
+type Param struct {
+	Name        string
+	Description string
+	Required    bool
+}
+
+// Documented is implemented by handlers that describe their routes.
+type Documented interface {
+	Docs() []Doc
+}
+
+type Route struct {
+	Method string
+	Path   string
+	Doc    *Doc
+	// Deprecated routes were registered through Deprecate.
+	Deprecated bool
+}
+
+type registry struct {
+	mu     sync.Mutex
+	routes []Route
+	docs   map[string]Doc
+}
+
+func newRegistry() *registry {
+	return &registry{docs: make(map[string]Doc)}
+}
+
+func (r *registry) add(method, path string, deprecated bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes = append(r.routes, Route{Method: method, Path: path, Deprecated: deprecated})
+}
+
+func (r *registry) document(doc Doc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.docs[doc.Method+" "+doc.Path] = doc
+}
+
+// list returns the routes sorted by path and method with their docs.
+func (r *registry) list() []Route {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	routes := make([]Route, len(r.routes))
+	for i, route := range r.routes {
+		if doc, ok := r.docs[route.Method+" "+route.Path]; ok {
+			route.Doc = &doc
+		}
+		routes[i] = route
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
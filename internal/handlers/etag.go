@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidETag = errors.New("If-Match must be a single entity tag")
+
+// ETag formats an entity version as a strong entity tag.
+func ETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// NotModified answers 304 when If-None-Match lists etag and reports whether
+// it did so.
+func NotModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			w.Header().Set("ETag", etag)
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// IfMatch returns the version required by the If-Match header. ok is false
+// when the header is absent or "*", which any existing entity satisfies.
+func IfMatch(r *http.Request) (version int, ok bool, err error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0, false, nil
+	}
+	unquoted, err := strconv.Unquote(header)
+	if err != nil {
+		return 0, false, ErrInvalidETag
+	}
+	version, err = strconv.Atoi(unquoted)
+	if err != nil {
+		return 0, false, ErrInvalidETag
+	}
+	return version, true, nil
+}
+
+// ExpectedVersion returns the version a write must match: the If-Match
+// header when sent, otherwise the version from the request body. It answers
+// 400 or 428 itself and then reports false.
+func ExpectedVersion(w http.ResponseWriter, r *http.Request, bodyVersion int) (int, bool) {
+	version, ok, err := IfMatch(r)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return 0, false
+	}
+	if ok {
+		return version, true
+	}
+	if bodyVersion > 0 {
+		return bodyVersion, true
+	}
+	WriteError(w, http.StatusPreconditionRequired, "If-Match header or version is required")
+	return 0, false
+}
+
+// WriteConflict answers a lost update: 412 when the client sent If-Match,
+// 409 when the version came from the body.
+func WriteConflict(w http.ResponseWriter, r *http.Request, message string) {
+	if _, ok, _ := IfMatch(r); ok {
+		WriteError(w, http.StatusPreconditionFailed, message)
+		return
+	}
+	WriteError(w, http.StatusConflict, message)
+}
+
+// CheckIfMatch answers 412 when If-Match names a version other than current
+// and reports whether the request may proceed.
+func CheckIfMatch(w http.ResponseWriter, r *http.Request, current int) bool {
+	version, ok, err := IfMatch(r)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+	if ok && version != current {
+		WriteError(w, http.StatusPreconditionFailed, "entity tag does not match")
+		return false
+	}
+	return true
+}
@@ -1,9 +1,18 @@
 package handlers
 
-import (
-	"github.com/julienschmidt/httprouter"
-)
-
 type Handler interface {
-	Register(router *httprouter.Router)
+	Register(router Router)
+	// Middleware is applied to every route the handler registers.
+	Middleware() []Middleware
+}
+
+// Mount registers handlers on router, each inside its own group carrying
+// the handler's middleware.
+func Mount(router Router, handlers ...Handler) {
+	for _, h := range handlers {
+		h.Register(router.Group("", h.Middleware()...))
+		if d, ok := h.(Documented); ok {
+			router.Document(d.Docs()...)
+		}
+	}
 }
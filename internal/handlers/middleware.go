@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"awesome-clean-arch/pkg/logging"
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"github.com/julienschmidt/httprouter"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength is the size of the request_id columns.
+const maxRequestIDLength = 128
+
+type ctxKey int
+
+const (
+	requestIDKey ctxKey = iota
+	routeOptionsKey
+)
+
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// NewRequestID returns a random id for requests that do not bring one.
+func NewRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// RequestID propagates the incoming X-Request-ID header or generates a new id.
+func RequestID() Middleware {
+	return func(next httprouter.Handle) httprouter.Handle {
+		return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+			id := r.Header.Get(RequestIDHeader)
+			if id == "" || len(id) > maxRequestIDLength {
+				id = NewRequestID()
+			}
+
+			w.Header().Set(RequestIDHeader, id)
+			next(w, r.WithContext(WithRequestID(r.Context(), id)), params)
+		}
+	}
+}
+
+// Recovery turns a panic in the handler chain into a 500 JSON response.
+func Recovery(logger *logging.Logger) Middleware {
+	return func(next httprouter.Handle) httprouter.Handle {
+		return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+			defer func() {
+				if rec := recover(); rec != nil {
+					if rec == http.ErrAbortHandler {
+						panic(rec)
+					}
+					logger.GetLoggerWithField("request_id", RequestIDFromContext(r.Context())).
+						Errorf("panic: %v\n%s", rec, debug.Stack())
+					WriteError(w, http.StatusInternalServerError, "internal server error")
+				}
+			}()
+			next(w, r, params)
+		}
+	}
+}
+
+// AccessLog logs one line per request with status, size and duration.
+func AccessLog(logger *logging.Logger) Middleware {
+	return func(next httprouter.Handle) httprouter.Handle {
+		return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+			start := time.Now()
+			rw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+
+			next(rw, r, params)
+
+			logger.GetLoggerWithField("request_id", RequestIDFromContext(r.Context())).
+				Infof("%s %s %d %dB %s %s", r.Method, r.URL.RequestURI(), rw.status, rw.size, time.Since(start), r.RemoteAddr)
+		}
+	}
+}
+
+// Timeout cancels the request context after d and answers 503 if the
+// handler has not responded by then. Routes registered with NoTimeout are
+// not limited.
+func Timeout(d time.Duration) Middleware {
+	return func(next httprouter.Handle) httprouter.Handle {
+		if d <= 0 {
+			return next
+		}
+		return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+			if HasRouteOption(r, NoTimeout) {
+				next(w, r, params)
+				return
+			}
+			h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				next(w, r, params)
+			})
+			w.Header().Set("Content-Type", "application/json")
+			http.TimeoutHandler(h, d, `{"error":"request timeout"}`).ServeHTTP(w, r)
+		}
+	}
+}
+
+// BodyLimit rejects request bodies larger than n bytes. Routes registered
+// with NoBodyLimit are not limited.
+func BodyLimit(n int64) Middleware {
+	return func(next httprouter.Handle) httprouter.Handle {
+		if n <= 0 {
+			return next
+		}
+		return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+			if HasRouteOption(r, NoBodyLimit) {
+				next(w, r, params)
+				return
+			}
+			if r.ContentLength > n {
+				WriteError(w, http.StatusRequestEntityTooLarge, "request body too large")
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, n)
+			next(w, r, params)
+		}
+	}
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	size        int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(b)
+	w.size += n
+	return n, err
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.wroteHeader = true
+		f.Flush()
+	}
+}
+
+// Hijack hands the connection over for WebSocket, the access log reports it
+// as 101.
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response does not support hijacking")
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil {
+		w.status = http.StatusSwitchingProtocols
+		w.wroteHeader = true
+	}
+	return conn, rw, err
+}
+
+// IsStream reports whether r asks for Server-Sent Events or a WebSocket.
+func IsStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream") ||
+		strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"awesome-clean-arch/internal/config"
+	"awesome-clean-arch/pkg/logging"
+	"awesome-clean-arch/pkg/ratelimit"
+	"github.com/julienschmidt/httprouter"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+)
+
+// RateLimitKeyFunc identifies the client of a request. It may return a
+// client specific limit that replaces the global one.
+type RateLimitKeyFunc func(r *http.Request) (key string, limit *ratelimit.Limit)
+
+type RateLimiter struct {
+	limiter ratelimit.Limiter
+	keyFunc RateLimitKeyFunc
+	logger  *logging.Logger
+	cfg     atomic.Pointer[config.RateLimitConfig]
+}
+
+func NewRateLimiter(limiter ratelimit.Limiter, cfg config.RateLimitConfig, keyFunc RateLimitKeyFunc, logger *logging.Logger) *RateLimiter {
+	rl := &RateLimiter{
+		limiter: limiter,
+		keyFunc: keyFunc,
+		logger:  logger,
+	}
+	rl.Update(cfg)
+	return rl
+}
+
+func (rl *RateLimiter) Update(cfg config.RateLimitConfig) {
+	rl.cfg.Store(&cfg)
+}
+
+func (rl *RateLimiter) Middleware() Middleware {
+	return func(next httprouter.Handle) httprouter.Handle {
+		return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+			cfg := rl.cfg.Load()
+			if !cfg.Enabled {
+				next(w, r, params)
+				return
+			}
+
+			limit := ratelimit.Limit{Rate: cfg.Rate, Burst: cfg.Burst}
+			key, override := rl.keyFunc(r)
+			if key == "" {
+				key = "ip:" + ClientIP(r)
+			}
+			if override != nil {
+				limit = *override
+			}
+
+			res, err := rl.limiter.Allow(r.Context(), key, limit)
+			if err != nil {
+				// Fail open: an unavailable limiter backend must not take the API down.
+				rl.logger.Error(err)
+				next(w, r, params)
+				return
+			}
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(int(math.Ceil(res.Reset.Seconds()))))
+
+			if !res.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(res.RetryAfter.Seconds()))))
+				WriteError(w, http.StatusTooManyRequests, "rate limit exceeded")
+				return
+			}
+
+			next(w, r, params)
+		}
+	}
+}
+
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
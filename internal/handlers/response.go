@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"awesome-clean-arch/pkg/validate"
+	"encoding/json"
+	"net/http"
+)
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type ValidationErrorResponse struct {
+	Error  string                `json:"error"`
+	Fields []validate.FieldError `json:"fields"`
+}
+
+func WriteError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: message})
+}
+
+// Validate checks v against its validation rules on routes registered with
+// StrictValidation and answers 422 when it breaks them. It reports whether
+// the request can go on.
+func Validate(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if !HasRouteOption(r, StrictValidation) {
+		return true
+	}
+	if err := validate.Struct(v); err != nil {
+		WriteValidationError(w, err)
+		return false
+	}
+	return true
+}
+
+// WriteValidationError answers 422 listing every invalid field of err.
+func WriteValidationError(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(ValidationErrorResponse{Error: "validation failed", Fields: validate.Fields(err)})
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+)
+
+type Middleware func(next httprouter.Handle) httprouter.Handle
+
+// RouteOption tells the global middleware how to treat the routes of a group.
+type RouteOption int
+
+const (
+	// NoReplay exempts POST routes from Idempotency-Key replay, e.g. when
+	// their response must not be stored.
+	NoReplay RouteOption = 1 << iota
+	// NoTimeout exempts long-lived routes, e.g. event streams, from Timeout.
+	NoTimeout
+	// NoBodyLimit exempts routes that bound their bodies themselves, e.g.
+	// uploads, from BodyLimit.
+	NoBodyLimit
+	// StrictValidation makes Validate reject request bodies that break the
+	// validation rules of their model. Older versions keep accepting them.
+	StrictValidation
+)
+
+// HasRouteOption reports whether the route serving r was registered with o.
+func HasRouteOption(r *http.Request, o RouteOption) bool {
+	options, _ := r.Context().Value(routeOptionsKey).(RouteOption)
+	return options&o != 0
+}
+
+// Chain wraps h so that the first middleware is the outermost one.
+func Chain(h httprouter.Handle, middleware ...Middleware) httprouter.Handle {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+	return h
+}
+
+// Router registers routes under a common path prefix and middleware stack.
+type Router interface {
+	Handle(method, path string, handle httprouter.Handle, middleware ...Middleware)
+	GET(path string, handle httprouter.Handle, middleware ...Middleware)
+	POST(path string, handle httprouter.Handle, middleware ...Middleware)
+	PUT(path string, handle httprouter.Handle, middleware ...Middleware)
+	PATCH(path string, handle httprouter.Handle, middleware ...Middleware)
+	DELETE(path string, handle httprouter.Handle, middleware ...Middleware)
+	Group(prefix string, middleware ...Middleware) Router
+	// With returns a group whose routes carry options.
+	With(options RouteOption) Router
+	// Document attaches descriptions to routes, paths are relative to the group.
+	Document(docs ...Doc)
+	// Routes lists every route registered through the root router and its groups.
+	Routes() []Route
+}
+
+type group struct {
+	router     *httprouter.Router
+	prefix     string
+	middleware []Middleware
+	registry   *registry
+	// deprecation is set on groups returned by Deprecate and their subgroups.
+	deprecation *deprecation
+	options     RouteOption
+}
+
+// NewRouter returns the root group of router with global middleware.
+func NewRouter(router *httprouter.Router, middleware ...Middleware) Router {
+	return &group{
+		router:     router,
+		middleware: middleware,
+		registry:   newRegistry(),
+	}
+}
+
+func (g *group) Handle(method, path string, handle httprouter.Handle, middleware ...Middleware) {
+	mw := make([]Middleware, 0, len(g.middleware)+len(middleware)+2)
+	if g.options != 0 {
+		// Outermost, so the global middleware sees the options.
+		mw = append(mw, withRouteOptions(g.options))
+	}
+	mw = append(mw, g.middleware...)
+	if g.deprecation != nil {
+		mw = append(mw, g.deprecation.middleware(method, g.prefix+path))
+	}
+	mw = append(mw, middleware...)
+	g.router.Handle(method, g.prefix+path, Chain(handle, mw...))
+	g.registry.add(method, g.prefix+path, g.deprecation != nil)
+}
+
+func (g *group) GET(path string, handle httprouter.Handle, middleware ...Middleware) {
+	g.Handle(http.MethodGet, path, handle, middleware...)
+}
+
+func (g *group) POST(path string, handle httprouter.Handle, middleware ...Middleware) {
+	g.Handle(http.MethodPost, path, handle, middleware...)
+}
+
+func (g *group) PUT(path string, handle httprouter.Handle, middleware ...Middleware) {
+	g.Handle(http.MethodPut, path, handle, middleware...)
+}
+
+func (g *group) PATCH(path string, handle httprouter.Handle, middleware ...Middleware) {
+	g.Handle(http.MethodPatch, path, handle, middleware...)
+}
+
+func (g *group) DELETE(path string, handle httprouter.Handle, middleware ...Middleware) {
+	g.Handle(http.MethodDelete, path, handle, middleware...)
+}
+
+func (g *group) Group(prefix string, middleware ...Middleware) Router {
+	mw := make([]Middleware, 0, len(g.middleware)+len(middleware))
+	mw = append(mw, g.middleware...)
+	mw = append(mw, middleware...)
+	return &group{
+		router:      g.router,
+		prefix:      g.prefix + prefix,
+		middleware:  mw,
+		registry:    g.registry,
+		deprecation: g.deprecation,
+		options:     g.options,
+	}
+}
+
+func (g *group) With(options RouteOption) Router {
+	c := *g
+	c.options |= options
+	return &c
+}
+
+func withRouteOptions(options RouteOption) Middleware {
+	return func(next httprouter.Handle) httprouter.Handle {
+		return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+			ctx := context.WithValue(r.Context(), routeOptionsKey, options)
+			next(w, r.WithContext(ctx), params)
+		}
+	}
+}
+
+func (g *group) Document(docs ...Doc) {
+	for _, doc := range docs {
+		doc.Path = g.prefix + doc.Path
+		g.registry.document(doc)
+	}
+}
+
+func (g *group) Routes() []Route {
+	return g.registry.list()
+}
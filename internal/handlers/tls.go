@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+)
+
+// ClientCertificate returns the verified leaf certificate of a mutual TLS client.
+// Certificates that were presented but not verified are ignored.
+func ClientCertificate(r *http.Request) (*x509.Certificate, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, false
+	}
+	return r.TLS.VerifiedChains[0][0], true
+}
+
+func ClientSubject(r *http.Request) (pkix.Name, bool) {
+	cert, ok := ClientCertificate(r)
+	if !ok {
+		return pkix.Name{}, false
+	}
+	return cert.Subject, true
+}
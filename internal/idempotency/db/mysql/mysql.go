@@ -0,0 +1,121 @@
+package mysql_idempotency
+
+import (
+	"awesome-clean-arch/internal/idempotency"
+	"awesome-clean-arch/pkg/client/mysql"
+	"awesome-clean-arch/pkg/logging"
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type mysqlRepository struct {
+	client mysql.Client
+	logger *logging.Logger
+}
+
+func formatQuery(q string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(q, "\t", ""), "\n", " ")
+}
+
+func (r *mysqlRepository) Reserve(ctx context.Context, rec idempotency.Record) (idempotency.Record, bool, error) {
+	dq := `DELETE FROM idempotency_key WHERE scope = ? AND idempotency_key = ? AND expires_at <= ?;`
+	q := `INSERT INTO idempotency_key (scope, idempotency_key, fingerprint, created_at, expires_at) VALUES (?, ?, ?, ?, ?);`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(dq)))
+
+	if _, err := r.client.ExecContext(ctx, dq, rec.Scope, rec.Key, rec.CreatedAt); err != nil {
+		r.logger.Error(err)
+		return idempotency.Record{}, false, err
+	}
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	_, err := r.client.ExecContext(ctx, q, rec.Scope, rec.Key, rec.Fingerprint, rec.CreatedAt, rec.ExpiresAt)
+	if err == nil {
+		return idempotency.Record{}, true, nil
+	}
+	if !mysql.IsDuplicateEntry(err) {
+		r.logger.Error(err)
+		return idempotency.Record{}, false, err
+	}
+
+	existing, err := r.findOne(ctx, rec.Scope, rec.Key)
+	if err != nil {
+		return idempotency.Record{}, false, err
+	}
+	return existing, false, nil
+}
+
+func (r *mysqlRepository) findOne(ctx context.Context, scope, key string) (idempotency.Record, error) {
+	q := `SELECT scope, idempotency_key, fingerprint, status_code, content_type, response_body, created_at, expires_at
+	FROM idempotency_key WHERE scope = ? AND idempotency_key = ?;`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	var rec idempotency.Record
+	var status sql.NullInt64
+	var contentType sql.NullString
+
+	err := r.client.QueryRowContext(ctx, q, scope, key).Scan(&rec.Scope, &rec.Key, &rec.Fingerprint, &status,
+		&contentType, &rec.Body, &rec.CreatedAt, &rec.ExpiresAt)
+	if err != nil {
+		r.logger.Error(err)
+		return idempotency.Record{}, err
+	}
+
+	rec.StatusCode = int(status.Int64)
+	rec.ContentType = contentType.String
+	return rec, nil
+}
+
+func (r *mysqlRepository) Complete(ctx context.Context, rec idempotency.Record) error {
+	q := `UPDATE idempotency_key SET status_code = ?, content_type = ?, response_body = ? WHERE scope = ? AND idempotency_key = ?;`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	_, err := r.client.ExecContext(ctx, q, rec.StatusCode, rec.ContentType, rec.Body, rec.Scope, rec.Key)
+	if err != nil {
+		r.logger.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *mysqlRepository) Release(ctx context.Context, scope, key string) error {
+	q := `DELETE FROM idempotency_key WHERE scope = ? AND idempotency_key = ?;`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	_, err := r.client.ExecContext(ctx, q, scope, key)
+	if err != nil {
+		r.logger.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *mysqlRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	q := `DELETE FROM idempotency_key WHERE expires_at < ?;`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	res, err := r.client.ExecContext(ctx, q, before)
+	if err != nil {
+		r.logger.Error(err)
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func NewMySQLRepository(client mysql.Client, logger *logging.Logger) idempotency.Repository {
+	return &mysqlRepository{
+		client: client,
+		logger: logger,
+	}
+}
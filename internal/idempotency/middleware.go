@@ -0,0 +1,191 @@
+package idempotency
+
+import (
+	"awesome-clean-arch/internal/auth"
+	"awesome-clean-arch/internal/handlers"
+	"awesome-clean-arch/pkg/logging"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/julienschmidt/httprouter"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	KeyHeader      = "Idempotency-Key"
+	ReplayedHeader = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+)
+
+// Store makes POST requests carrying an Idempotency-Key safe to retry: the
+// first response is kept for the TTL and replayed to later requests with the
+// same key and body. Keys are scoped per API key.
+type Store struct {
+	repository Repository
+	ttl        time.Duration
+	logger     *logging.Logger
+}
+
+func NewStore(repository Repository, ttl time.Duration, logger *logging.Logger) *Store {
+	return &Store{
+		repository: repository,
+		ttl:        ttl,
+		logger:     logger,
+	}
+}
+
+func (s *Store) Middleware() handlers.Middleware {
+	return func(next httprouter.Handle) httprouter.Handle {
+		return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+			key := r.Header.Get(KeyHeader)
+			if key == "" || r.Method != http.MethodPost || handlers.HasRouteOption(r, handlers.NoReplay) {
+				next(w, r, params)
+				return
+			}
+			if len(key) > maxKeyLength {
+				handlers.WriteError(w, http.StatusBadRequest, KeyHeader+" is too long")
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					handlers.WriteError(w, http.StatusRequestEntityTooLarge, "request body too large")
+					return
+				}
+				handlers.WriteError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			now := time.Now().UTC()
+			rec := Record{
+				Scope:       scope(r),
+				Key:         key,
+				Fingerprint: fingerprint(r, body),
+				CreatedAt:   now,
+				ExpiresAt:   now.Add(s.ttl),
+			}
+
+			existing, created, err := s.repository.Reserve(r.Context(), rec)
+			if err != nil {
+				s.logger.Error(err)
+				handlers.WriteError(w, http.StatusInternalServerError, "idempotency check failed")
+				return
+			}
+			if !created {
+				s.replay(w, rec, existing)
+				return
+			}
+
+			s.serve(w, r, params, next, rec)
+		}
+	}
+}
+
+func (s *Store) replay(w http.ResponseWriter, rec, existing Record) {
+	switch {
+	case existing.Fingerprint != rec.Fingerprint:
+		handlers.WriteError(w, http.StatusUnprocessableEntity, KeyHeader+" was already used with a different request")
+	case !existing.Completed():
+		w.Header().Set("Retry-After", "1")
+		handlers.WriteError(w, http.StatusConflict, "a request with this "+KeyHeader+" is still being processed")
+	default:
+		if existing.ContentType != "" {
+			w.Header().Set("Content-Type", existing.ContentType)
+		}
+		w.Header().Set(ReplayedHeader, "true")
+		w.WriteHeader(existing.StatusCode)
+		w.Write(existing.Body)
+	}
+}
+
+// serve runs the handler and keeps its response. Server errors and panics
+// release the key so the client can retry. Any other response is kept even
+// when the client is gone, as the write it reports happened.
+func (s *Store) serve(w http.ResponseWriter, r *http.Request, params httprouter.Params, next httprouter.Handle, rec Record) {
+	rw := &recordingWriter{ResponseWriter: w}
+
+	failed := true
+	defer func() {
+		if !failed {
+			return
+		}
+		ctx, cancel := storeContext()
+		defer cancel()
+		if err := s.repository.Release(ctx, rec.Scope, rec.Key); err != nil {
+			s.logger.Error(err)
+		}
+	}()
+
+	next(rw, r, params)
+
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	if rw.status >= http.StatusInternalServerError {
+		return
+	}
+	failed = false
+
+	rec.StatusCode = rw.status
+	rec.ContentType = rw.Header().Get("Content-Type")
+	rec.Body = rw.body.Bytes()
+	ctx, cancel := storeContext()
+	defer cancel()
+	if err := s.repository.Complete(ctx, rec); err != nil {
+		s.logger.Error(err)
+	}
+}
+
+// storeContext bounds a write of the outcome of a request, whose own context
+// may already be gone.
+func storeContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), 5*time.Second)
+}
+
+func scope(r *http.Request) string {
+	if a, ok := auth.FromContext(r.Context()); ok {
+		return "key:" + strconv.Itoa(a.ID)
+	}
+	return "ip:" + handlers.ClientIP(r)
+}
+
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *recordingWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,20 @@
+package idempotency
+
+import "time"
+
+// Record is a stored request and, once the handler finished, its response.
+// A zero StatusCode marks a request that is still being processed.
+type Record struct {
+	Scope       string
+	Key         string
+	Fingerprint string
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+func (r Record) Completed() bool {
+	return r.StatusCode != 0
+}
@@ -0,0 +1,17 @@
+package idempotency
+
+import (
+	"context"
+	"time"
+)
+
+type Repository interface {
+	// Reserve stores rec unless an unexpired record with the same scope and
+	// key exists, in which case that record is returned with created false.
+	Reserve(ctx context.Context, rec Record) (existing Record, created bool, err error)
+	Complete(ctx context.Context, rec Record) error
+	// Release forgets a reservation so the request can be retried.
+	Release(ctx context.Context, scope, key string) error
+	// Purge removes records that expired before the given time.
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
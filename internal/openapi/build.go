@@ -0,0 +1,163 @@
+package openapi
+
+import (
+	"awesome-clean-arch/internal/handlers"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	apiKeyScheme    = "apiKey"
+	bearerScheme    = "bearerAuth"
+	signatureScheme = "signature"
+)
+
+// Build describes the documented routes. Routes registered without a Doc
+// are left out and returned as missing.
+func Build(info Info, routes []handlers.Route) (doc *Document, missing []handlers.Route) {
+	s := newSchemas()
+	errorSchema := s.of(handlers.ErrorResponse{})
+	validationSchema := s.of(handlers.ValidationErrorResponse{})
+
+	doc = &Document{
+		OpenAPI: "3.0.3",
+		Info:    info,
+		Paths:   make(map[string]*PathItem),
+		Components: Components{
+			Schemas: s.components,
+			SecuritySchemes: map[string]SecurityScheme{
+				apiKeyScheme: {Type: "apiKey", Name: "X-API-Key", In: "header"},
+				bearerScheme: {Type: "http", Scheme: "bearer", BearerFormat: "JWT",
+					Description: "Short-lived token issued by POST /token"},
+				signatureScheme: {Type: "apiKey", Name: "X-Signature", In: "header",
+					Description: "HMAC-SHA256 request signature, sent with X-Signature-Key-Id, X-Signature-Timestamp and X-Signature-Nonce"},
+			},
+		},
+	}
+
+	for _, route := range routes {
+		if route.Doc == nil {
+			missing = append(missing, route)
+			continue
+		}
+
+		path, params := convertPath(route.Path)
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = &PathItem{}
+			doc.Paths[path] = item
+		}
+
+		op := operation(s, route.Method, path, *route.Doc, errorSchema, validationSchema)
+		op.Parameters = append(params, op.Parameters...)
+		op.Deprecated = route.Deprecated
+		(*item)[strings.ToLower(route.Method)] = op
+	}
+
+	return doc, missing
+}
+
+func operation(s *schemas, method, path string, d handlers.Doc, errorSchema, validationSchema *Schema) *Operation {
+	op := &Operation{
+		OperationID: operationID(method, path),
+		Summary:     d.Summary,
+		Description: d.Description,
+		Tags:        d.Tags,
+		Responses:   make(map[string]*Response),
+	}
+
+	for _, q := range d.Query {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name: q.Name, In: "query", Description: q.Description, Required: q.Required,
+			Schema: &Schema{Type: "string"},
+		})
+	}
+
+	if d.Request != nil {
+		op.RequestBody = &RequestBody{Required: true, Content: jsonContent(s.of(d.Request))}
+		op.Responses["400"] = errorResponse("Malformed request body", errorSchema)
+		op.Responses["422"] = &Response{Description: "Validation failed", Content: jsonContent(validationSchema)}
+	}
+
+	status := d.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := &Response{Description: http.StatusText(status)}
+	if d.Response != nil {
+		success.Content = jsonContent(s.of(d.Response))
+	}
+	op.Responses[strconv.Itoa(status)] = success
+
+	if d.Conditional {
+		if method == http.MethodGet {
+			op.Parameters = append(op.Parameters, headerParam("If-None-Match", "Answer 304 when the entity tag still matches"))
+			success.Headers = map[string]Header{"ETag": {Description: "Entity version", Schema: &Schema{Type: "string"}}}
+			op.Responses["304"] = &Response{Description: "Not Modified"}
+		} else {
+			op.Parameters = append(op.Parameters, headerParam("If-Match", "Only apply the change to this entity version"))
+			op.Responses["409"] = errorResponse("The entity changed since it was read", errorSchema)
+			op.Responses["412"] = errorResponse("If-Match does not match the current entity version", errorSchema)
+			if method != http.MethodDelete && method != http.MethodPatch {
+				op.Responses["428"] = errorResponse("Neither If-Match nor version was sent", errorSchema)
+			}
+		}
+	}
+
+	if d.Idempotent {
+		op.Parameters = append(op.Parameters, headerParam("Idempotency-Key", "Makes retries of this request return the first response"))
+		op.Responses["422"] = errorResponse("Validation failed, or the Idempotency-Key was used with a different request", errorSchema)
+	}
+
+	if d.Scope != "" {
+		op.Security = []map[string][]string{{apiKeyScheme: {}}, {bearerScheme: {}}, {signatureScheme: {}}}
+		scope := "Requires scope `" + d.Scope + "`."
+		if op.Description == "" {
+			op.Description = scope
+		} else {
+			op.Description += "\n\n" + scope
+		}
+		op.Responses["401"] = errorResponse("Missing or invalid credentials", errorSchema)
+		op.Responses["403"] = errorResponse("The key lacks the required scope", errorSchema)
+	}
+
+	return op
+}
+
+// convertPath turns httprouter parameters into OpenAPI templates.
+func convertPath(path string) (string, []Parameter) {
+	var params []Parameter
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		if len(seg) > 1 && (seg[0] == ':' || seg[0] == '*') {
+			name := seg[1:]
+			segments[i] = "{" + name + "}"
+			params = append(params, Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, seg := range strings.FieldsFunc(path, func(r rune) bool {
+		return r == '/' || r == '{' || r == '}' || r == '-' || r == '_' || r == '.'
+	}) {
+		b.WriteString(strings.ToUpper(seg[:1]) + seg[1:])
+	}
+	return b.String()
+}
+
+func jsonContent(schema *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: schema}}
+}
+
+func errorResponse(description string, schema *Schema) *Response {
+	return &Response{Description: description, Content: jsonContent(schema)}
+}
+
+func headerParam(name, description string) Parameter {
+	return Parameter{Name: name, In: "header", Description: description, Schema: &Schema{Type: "string"}}
+}
@@ -0,0 +1,78 @@
+package openapi
+
+import (
+	"awesome-clean-arch/internal/handlers"
+	"awesome-clean-arch/pkg/logging"
+	_ "embed"
+	"encoding/json"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"sync"
+)
+
+const (
+	specURL = "/openapi.json"
+	docsURL = "/docs"
+)
+
+//go:embed docs.html
+var docsPage []byte
+
+var _ handlers.Handler = &handler{}
+
+type handler struct {
+	logger *logging.Logger
+	info   Info
+	router handlers.Router
+
+	once sync.Once
+	spec []byte
+	err  error
+}
+
+// NewHandler serves the specification of the routes registered on router.
+// It is built on first request, once every handler has been mounted.
+func NewHandler(logger *logging.Logger, info Info, router handlers.Router) handlers.Handler {
+	return &handler{
+		logger: logger,
+		info:   info,
+		router: router,
+	}
+}
+
+func (h *handler) Middleware() []handlers.Middleware {
+	return nil
+}
+
+func (h *handler) Register(router handlers.Router) {
+	router.GET(specURL, h.GetSpec)
+	router.GET(docsURL, h.GetDocs)
+}
+
+func (h *handler) Docs() []handlers.Doc {
+	return []handlers.Doc{
+		{Method: http.MethodGet, Path: specURL, Summary: "OpenAPI specification of this API", Tags: []string{"meta"}},
+		{Method: http.MethodGet, Path: docsURL, Summary: "Interactive API documentation", Tags: []string{"meta"}},
+	}
+}
+
+func (h *handler) GetSpec(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	h.once.Do(func() {
+		doc, _ := Build(h.info, h.router.Routes())
+		h.spec, h.err = json.Marshal(doc)
+	})
+	if h.err != nil {
+		handlers.WriteError(w, http.StatusInternalServerError, h.err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(h.spec)
+}
+
+func (h *handler) GetDocs(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(docsPage)
+}
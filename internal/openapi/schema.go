@@ -0,0 +1,160 @@
+package openapi
+
+import (
+	"awesome-clean-arch/pkg/validate"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemas turns Go types into schemas, registering named structs as
+// components referenced by $ref.
+type schemas struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func newSchemas() *schemas {
+	return &schemas{
+		components: make(map[string]*Schema),
+		names:      make(map[reflect.Type]string),
+	}
+}
+
+func (s *schemas) of(v interface{}) *Schema {
+	return s.schema(reflect.TypeOf(v))
+}
+
+func (s *schemas) schema(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		schema := s.schema(t.Elem())
+		if schema.Ref != "" {
+			return schema
+		}
+		schema.Nullable = true
+		return schema
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + s.component(t)}
+	}
+	return &Schema{}
+}
+
+func (s *schemas) component(t reflect.Type) string {
+	if name, ok := s.names[t]; ok {
+		return name
+	}
+
+	name := t.Name()
+	if _, taken := s.components[name]; taken {
+		pkg := t.PkgPath()
+		name = strings.ReplaceAll(pkg[strings.LastIndex(pkg, "/")+1:], "_", "") + "." + name
+	}
+	s.names[t] = name
+	// Reserve the name first so recursive types terminate.
+	s.components[name] = &Schema{}
+	*s.components[name] = *s.object(t)
+	return name
+}
+
+func (s *schemas) object(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	s.fields(t, schema)
+	return schema
+}
+
+func (s *schemas) fields(t reflect.Type, schema *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || !field.IsExported() {
+			continue
+		}
+		if field.Anonymous && tag == "" && field.Type.Kind() == reflect.Struct {
+			s.fields(field.Type, schema)
+			continue
+		}
+
+		name := validate.FieldName(field)
+		prop := s.schema(field.Type)
+		for _, rule := range validate.Rules(field.Tag.Get(validate.TagName)) {
+			if rule.Name == "required" {
+				schema.Required = append(schema.Required, name)
+				continue
+			}
+			if prop.Ref == "" {
+				applyRule(prop, rule)
+			}
+		}
+		schema.Properties[name] = prop
+	}
+}
+
+func applyRule(schema *Schema, rule validate.Rule) {
+	switch rule.Name {
+	case "oneof":
+		schema.Enum = strings.Fields(rule.Value)
+	case "min", "max":
+		n, err := strconv.ParseFloat(rule.Value, 64)
+		if err != nil {
+			return
+		}
+		i := int(n)
+		switch schema.Type {
+		case "string":
+			if rule.Name == "min" {
+				schema.MinLength = &i
+			} else {
+				schema.MaxLength = &i
+			}
+		case "array":
+			if rule.Name == "min" {
+				schema.MinItems = &i
+			} else {
+				schema.MaxItems = &i
+			}
+		case "integer", "number":
+			if rule.Name == "min" {
+				schema.Minimum = &n
+			} else {
+				schema.Maximum = &n
+			}
+		}
+	}
+}
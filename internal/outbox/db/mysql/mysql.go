@@ -0,0 +1,183 @@
+package mysql_outbox
+
+import (
+	"awesome-clean-arch/internal/outbox"
+	"awesome-clean-arch/pkg/client/mysql"
+	"awesome-clean-arch/pkg/logging"
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const messageColumns = `id, aggregate_type, aggregate_id, action, payload, request_id, created_at, attempts, next_attempt_at,
+	last_error, published_to, published_at, dead_at`
+
+type mysqlRepository struct {
+	client mysql.Client
+	logger *logging.Logger
+}
+
+func formatQuery(q string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(q, "\t", ""), "\n", " ")
+}
+
+func (r *mysqlRepository) Add(ctx context.Context, tx outbox.Execer, messages ...outbox.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	q := `INSERT INTO outbox (aggregate_type, aggregate_id, action, payload, request_id, created_at, next_attempt_at)
+	VALUES ` + strings.TrimSuffix(strings.Repeat("(?, ?, ?, ?, ?, ?, ?), ", len(messages)), ", ") + `;`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	args := make([]interface{}, 0, 7*len(messages))
+	for _, m := range messages {
+		var payload interface{}
+		if len(m.Payload) > 0 {
+			payload = string(m.Payload)
+		}
+		args = append(args, m.AggregateType, m.AggregateID, m.Action, payload, m.RequestID, m.CreatedAt, m.NextAttemptAt)
+	}
+
+	if _, err := tx.ExecContext(ctx, q, args...); err != nil {
+		r.logger.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *mysqlRepository) FindPending(ctx context.Context, afterID int64, now time.Time, limit int) ([]outbox.Message, error) {
+	q := `SELECT ` + messageColumns + ` FROM outbox o
+	WHERE published_at IS NULL AND dead_at IS NULL AND id > ? AND NOT EXISTS (
+		SELECT 1 FROM outbox b
+		WHERE b.aggregate_type = o.aggregate_type AND b.aggregate_id = o.aggregate_id AND b.id <= o.id
+			AND b.published_at IS NULL AND b.dead_at IS NULL AND b.next_attempt_at > ?
+	)
+	ORDER BY id LIMIT ` + strconv.Itoa(limit) + `;`
+
+	return r.findMany(ctx, q, afterID, now)
+}
+
+func (r *mysqlRepository) FindAfter(ctx context.Context, afterID int64, limit int) ([]outbox.Message, error) {
+	q := `SELECT ` + messageColumns + ` FROM outbox WHERE id > ? ORDER BY id LIMIT ` + strconv.Itoa(limit) + `;`
+
+	return r.findMany(ctx, q, afterID)
+}
+
+func (r *mysqlRepository) findMany(ctx context.Context, q string, args ...interface{}) ([]outbox.Message, error) {
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	rows, err := r.client.QueryContext(ctx, q, args...)
+	if err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := make([]outbox.Message, 0)
+
+	for rows.Next() {
+		var m outbox.Message
+		var payload []byte
+		var publishedTo string
+		var publishedAt, deadAt sql.NullTime
+
+		err := rows.Scan(&m.ID, &m.AggregateType, &m.AggregateID, &m.Action, &payload, &m.RequestID, &m.CreatedAt,
+			&m.Attempts, &m.NextAttemptAt, &m.LastError, &publishedTo, &publishedAt, &deadAt)
+		if err != nil {
+			r.logger.Error(err)
+			return nil, err
+		}
+
+		m.Type = m.AggregateType + "." + m.Action
+		m.Payload = payload
+		if publishedTo != "" {
+			m.PublishedTo = strings.Split(publishedTo, ",")
+		}
+		if publishedAt.Valid {
+			m.PublishedAt = &publishedAt.Time
+		}
+		if deadAt.Valid {
+			m.DeadAt = &deadAt.Time
+		}
+
+		messages = append(messages, m)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+func (r *mysqlRepository) LastID(ctx context.Context) (int64, error) {
+	q := `SELECT id FROM outbox ORDER BY id DESC LIMIT 1;`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	var id int64
+	if err := r.client.QueryRowContext(ctx, q).Scan(&id); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		r.logger.Error(err)
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r *mysqlRepository) MarkPublished(ctx context.Context, ID int64, at time.Time) error {
+	q := `UPDATE outbox SET published_at = ?, last_error = '' WHERE id = ?;`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	if _, err := r.client.ExecContext(ctx, q, at, ID); err != nil {
+		r.logger.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *mysqlRepository) SaveFailure(ctx context.Context, m outbox.Message) error {
+	q := `UPDATE outbox SET attempts = ?, next_attempt_at = ?, last_error = ?, published_to = ?, dead_at = ? WHERE id = ?;`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	_, err := r.client.ExecContext(ctx, q, m.Attempts, m.NextAttemptAt, m.LastError, strings.Join(m.PublishedTo, ","), m.DeadAt, m.ID)
+	if err != nil {
+		r.logger.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *mysqlRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	q := `DELETE FROM outbox WHERE published_at IS NOT NULL AND published_at < ?;`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	res, err := r.client.ExecContext(ctx, q, before)
+	if err != nil {
+		r.logger.Error(err)
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func NewMySQLRepository(client mysql.Client, logger *logging.Logger) outbox.Repository {
+	return &mysqlRepository{
+		client: client,
+		logger: logger,
+	}
+}
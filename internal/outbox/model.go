@@ -0,0 +1,68 @@
+package outbox
+
+import (
+	"awesome-clean-arch/internal/handlers"
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Aggregates whose changes go through the outbox.
+const (
+	AggregateUser     = "user"
+	AggregateProfile  = "profile"
+	AggregateUserData = "user_data"
+)
+
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionRestore = "restore"
+)
+
+// Message is a change of an aggregate, stored in the transaction of the
+// change and published by the relay once committed. Payload is the state of
+// the aggregate after the change, empty for deletions.
+type Message struct {
+	ID            int64           `json:"id"`
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	Action        string          `json:"action"`
+	Payload       json.RawMessage `json:"payload,omitempty"`
+	RequestID     string          `json:"request_id,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+
+	// Attempts, NextAttemptAt and LastError track failed publications,
+	// PublishedTo lists the sinks that took the message already. DeadAt is set
+	// once the message ran out of attempts, it is not published again.
+	Attempts      int        `json:"-"`
+	NextAttemptAt time.Time  `json:"-"`
+	LastError     string     `json:"-"`
+	PublishedTo   []string   `json:"-"`
+	PublishedAt   *time.Time `json:"-"`
+	DeadAt        *time.Time `json:"-"`
+}
+
+// NewMessage describes the action on an aggregate, made by the request of ctx
+// if any. payload is nil for deletions.
+func NewMessage(ctx context.Context, aggregateType, aggregateID, action string, payload interface{}) (Message, error) {
+	now := time.Now().UTC()
+	m := Message{
+		Type:          aggregateType + "." + action,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		Action:        action,
+		RequestID:     handlers.RequestIDFromContext(ctx),
+		CreatedAt:     now,
+		NextAttemptAt: now,
+	}
+	if payload != nil {
+		var err error
+		if m.Payload, err = json.Marshal(payload); err != nil {
+			return Message{}, err
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,168 @@
+package outbox
+
+import (
+	"awesome-clean-arch/pkg/logging"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Sink receives the published messages. A message that failed is published
+// again to the sinks that did not take it yet, so sinks see each message at
+// least once.
+type Sink interface {
+	Publish(ctx context.Context, m Message) error
+}
+
+// SinkFunc adapts a function to a Sink.
+type SinkFunc func(ctx context.Context, m Message) error
+
+func (f SinkFunc) Publish(ctx context.Context, m Message) error {
+	return f(ctx, m)
+}
+
+// Options tune the relay.
+type Options struct {
+	PollInterval time.Duration
+	BatchSize    int
+	// Backoff is the delay before publishing a failed message again, doubled
+	// for each further failure up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// MaxAttempts is the number of attempts before a message is dead.
+	MaxAttempts int
+}
+
+type sink struct {
+	name string
+	sink Sink
+}
+
+// Relay publishes the committed messages of the outbox to its sinks, in the
+// order they were added. A message that failed holds back the later messages
+// of the same aggregate until it is published or dead, others go on.
+type Relay struct {
+	repository Repository
+	options    Options
+	logger     *logging.Logger
+	sinks      []sink
+}
+
+func NewRelay(repository Repository, options Options, logger *logging.Logger) *Relay {
+	return &Relay{
+		repository: repository,
+		options:    options,
+		logger:     logger,
+	}
+}
+
+// Add registers a sink, sinks are published to in the order they were added.
+// The name records which sinks took a message, it must not contain a comma.
+// It must be called before Run.
+func (r *Relay) Add(name string, s Sink) {
+	r.sinks = append(r.sinks, sink{name: name, sink: s})
+}
+
+// Run publishes pending messages every poll interval until ctx is done.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.options.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		r.relay(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Relay) relay(ctx context.Context) {
+	// A message that fails holds back the later messages of its aggregate for
+	// the rest of the pass, also on the following pages.
+	blocked := make(map[string]bool)
+	var afterID int64
+
+	for ctx.Err() == nil {
+		pending, err := r.repository.FindPending(ctx, afterID, time.Now().UTC(), r.options.BatchSize)
+		if err != nil || len(pending) == 0 {
+			return
+		}
+
+		for _, m := range pending {
+			afterID = m.ID
+
+			aggregate := m.AggregateType + "/" + m.AggregateID
+			if blocked[aggregate] {
+				continue
+			}
+
+			if err = r.publish(ctx, &m); err != nil {
+				m.Attempts++
+				m.LastError = errorText(err)
+				if m.Attempts >= r.options.MaxAttempts {
+					now := time.Now().UTC()
+					m.DeadAt = &now
+					r.logger.Warnf("outbox: message %d (%s %s) is dead after %d attempts: %s",
+						m.ID, m.Type, m.AggregateID, m.Attempts, err)
+				} else {
+					blocked[aggregate] = true
+					m.NextAttemptAt = time.Now().UTC().Add(r.backoff(m.Attempts))
+					r.logger.Warnf("outbox: failed to publish message %d (%s %s), attempt %d: %s",
+						m.ID, m.Type, m.AggregateID, m.Attempts, err)
+				}
+				if err = r.repository.SaveFailure(ctx, m); err != nil {
+					r.logger.Errorf("outbox: failed to save message %d: %s", m.ID, err)
+				}
+				continue
+			}
+
+			if err = r.repository.MarkPublished(ctx, m.ID, time.Now().UTC()); err != nil {
+				r.logger.Errorf("outbox: failed to mark message %d published: %s", m.ID, err)
+				return
+			}
+		}
+
+		// A short batch leaves nothing to publish before the next poll.
+		if len(pending) < r.options.BatchSize {
+			return
+		}
+	}
+}
+
+// publish skips the sinks m was published to and adds those that take it.
+func (r *Relay) publish(ctx context.Context, m *Message) error {
+	for _, s := range r.sinks {
+		if slices.Contains(m.PublishedTo, s.name) {
+			continue
+		}
+		if err := s.sink.Publish(ctx, *m); err != nil {
+			return fmt.Errorf("%s: %w", s.name, err)
+		}
+		m.PublishedTo = append(m.PublishedTo, s.name)
+	}
+	return nil
+}
+
+func (r *Relay) backoff(attempts int) time.Duration {
+	delay := r.options.Backoff
+	for i := 1; i < attempts && delay < r.options.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > r.options.MaxBackoff {
+		delay = r.options.MaxBackoff
+	}
+	return delay
+}
+
+// errorText fits err into the last_error column.
+func errorText(err error) string {
+	text := err.Error()
+	if len(text) > 1024 {
+		text = text[:1024]
+	}
+	return text
+}
@@ -0,0 +1,150 @@
+package outbox
+
+import (
+	"awesome-clean-arch/pkg/logging"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryRepository keeps messages in memory, it implements what the relay
+// uses.
+type memoryRepository struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func (r *memoryRepository) Add(ctx context.Context, tx Execer, messages ...Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, m := range messages {
+		m.ID = int64(len(r.messages) + 1)
+		r.messages = append(r.messages, m)
+	}
+	return nil
+}
+
+func (r *memoryRepository) FindPending(ctx context.Context, afterID int64, now time.Time, limit int) ([]Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var pending []Message
+	for _, m := range r.messages {
+		if m.ID > afterID && m.PublishedAt == nil && m.DeadAt == nil && !m.NextAttemptAt.After(now) && len(pending) < limit {
+			m.PublishedTo = append([]string(nil), m.PublishedTo...)
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+func (r *memoryRepository) FindAfter(ctx context.Context, afterID int64, limit int) ([]Message, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) LastID(ctx context.Context) (int64, error) {
+	return int64(len(r.messages)), nil
+}
+
+func (r *memoryRepository) MarkPublished(ctx context.Context, ID int64, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages[ID-1].PublishedAt = &at
+	return nil
+}
+
+func (r *memoryRepository) SaveFailure(ctx context.Context, m Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages[m.ID-1] = m
+	return nil
+}
+
+func (r *memoryRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+// makeDue lets the failed messages be published again right away.
+func (r *memoryRepository) makeDue() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.messages {
+		r.messages[i].NextAttemptAt = time.Time{}
+	}
+}
+
+// countingSink counts the messages it is given and fails while failing is set.
+type countingSink struct {
+	calls   int
+	failing bool
+}
+
+func (s *countingSink) Publish(ctx context.Context, m Message) error {
+	s.calls++
+	if s.failing {
+		return errors.New("unavailable")
+	}
+	return nil
+}
+
+var testOptions = Options{
+	PollInterval: time.Second,
+	BatchSize:    10,
+	Backoff:      time.Second,
+	MaxBackoff:   time.Minute,
+	MaxAttempts:  3,
+}
+
+func newTestRelay(t *testing.T) (*Relay, *memoryRepository, *countingSink, *countingSink) {
+	t.Helper()
+	repo := &memoryRepository{}
+	if err := repo.Add(context.Background(), nil, Message{AggregateType: AggregateUser, AggregateID: "1", Action: ActionCreate}); err != nil {
+		t.Fatal(err)
+	}
+
+	relay := NewRelay(repo, testOptions, logging.GetLogger())
+	first, second := &countingSink{}, &countingSink{failing: true}
+	relay.Add("first", first)
+	relay.Add("second", second)
+	return relay, repo, first, second
+}
+
+func TestRetryOnlyPublishesToFailedSinks(t *testing.T) {
+	relay, repo, first, second := newTestRelay(t)
+
+	relay.relay(context.Background())
+	repo.makeDue()
+	second.failing = false
+	relay.relay(context.Background())
+
+	if first.calls != 1 {
+		t.Errorf("the sink that took the message was called %d times, want 1", first.calls)
+	}
+	if second.calls != 2 {
+		t.Errorf("the failing sink was called %d times, want 2", second.calls)
+	}
+	if repo.messages[0].PublishedAt == nil {
+		t.Error("the message was not marked published")
+	}
+}
+
+func TestMessageIsDeadAfterMaxAttempts(t *testing.T) {
+	relay, repo, _, second := newTestRelay(t)
+
+	for i := 0; i < testOptions.MaxAttempts+2; i++ {
+		relay.relay(context.Background())
+		repo.makeDue()
+	}
+
+	if second.calls != testOptions.MaxAttempts {
+		t.Errorf("got %d attempts, want %d", second.calls, testOptions.MaxAttempts)
+	}
+	m := repo.messages[0]
+	if m.DeadAt == nil {
+		t.Error("the message is not dead")
+	}
+	if m.PublishedAt != nil {
+		t.Error("a dead message must not be marked published")
+	}
+}
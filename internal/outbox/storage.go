@@ -0,0 +1,34 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Execer runs a statement, typically in the transaction of a change.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+type Repository interface {
+	// Add stores messages with tx, so they are committed or rolled back
+	// together with the change they describe.
+	Add(ctx context.Context, tx Execer, messages ...Message) error
+	// FindPending lists the unpublished messages after afterID that are due
+	// at now and not dead, in the order they were added. Messages behind one of the same
+	// aggregate that is not due yet are left out, so they stay in order.
+	FindPending(ctx context.Context, afterID int64, now time.Time, limit int) ([]Message, error)
+	// FindAfter lists the messages after afterID, published or not, in the
+	// order they were added.
+	FindAfter(ctx context.Context, afterID int64, limit int) ([]Message, error)
+	// LastID returns the ID of the last message added, 0 when there is none.
+	LastID(ctx context.Context) (int64, error)
+	MarkPublished(ctx context.Context, ID int64, at time.Time) error
+	// SaveFailure stores the attempts, next attempt, last error, sinks
+	// published to and death of m.
+	SaveFailure(ctx context.Context, m Message) error
+	// Purge deletes the messages published before before, dead messages are
+	// kept.
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
@@ -1,17 +1,31 @@
 package mysql_profile
 
 import (
+	"awesome-clean-arch/internal/outbox"
 	"awesome-clean-arch/internal/profile"
 	"awesome-clean-arch/pkg/client/mysql"
 	"awesome-clean-arch/pkg/logging"
 	"context"
+	"database/sql"
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
+)
+
+const (
+	// profileVersion changes with any of the rows a profile is made of.
+	profileVersion = `user.version + user_profile.version + user_data.version`
+	profileColumns = `user.username, user_profile.user_id, user_profile.first_name, user_profile.last_name,
+	user_profile.phone, user_profile.address, user_profile.city, user_data.school, ` + profileVersion + `,
+	COALESCE(user_profile.deleted_at, user.deleted_at)`
+	profileTables = `user JOIN user_profile ON user.id = user_profile.user_id JOIN user_data ON user.id = user_data.user_id`
+	notDeleted    = `user.deleted_at IS NULL AND user_profile.deleted_at IS NULL`
 )
 
 type mysqlRepository struct {
 	client mysql.Client
+	outbox outbox.Repository
 	logger *logging.Logger
 }
 
@@ -19,24 +33,144 @@ func formatQuery(q string) string {
 	return strings.ReplaceAll(strings.ReplaceAll(q, "\t", ""), "\n", " ")
 }
 
+// Create inserts the user, its profile and user data in one transaction.
 func (r *mysqlRepository) Create(ctx context.Context, p profile.Profile) (string, error) {
-	q := `START TRANSACTION;
-INSERT INTO user (username) VALUES (?);
-SET @user_id = LAST_INSERT_ID();
-INSERT INTO user_profile (user_id, first_name, last_name, phone, address, city) 
-VALUES (@user_id, ?, ?, ?, ?, ?);
-INSERT INTO user_data (user_id, school) VALUES (@user_id, ?);
-COMMIT;
-`
+	tx, err := r.client.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error(err)
+		return "", err
+	}
+	defer tx.Rollback()
+
+	userID, err := r.insert(ctx, tx, p)
+	if err != nil {
+		return "", err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.logger.Error(err)
+		return "", err
+	}
+
+	return userID, nil
+}
+
+func (r *mysqlRepository) CreateBatch(ctx context.Context, profiles []profile.Profile, dryRun bool) ([]string, error) {
+	if len(profiles) == 0 {
+		return nil, nil
+	}
+
+	taken, err := r.takenUsernames(ctx, profiles)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(profiles))
+	if dryRun {
+		for i, p := range profiles {
+			if !taken[strings.ToLower(p.Username)] {
+				ids[i] = profile.DryRunID
+			}
+		}
+		return ids, nil
+	}
+
+	tx, err := r.client.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// A username taken since it was looked up only undoes its own row.
+	sq := `SAVEPOINT batch_row;`
+	rq := `ROLLBACK TO SAVEPOINT batch_row;`
+
+	for i, p := range profiles {
+		if taken[strings.ToLower(p.Username)] {
+			continue
+		}
+
+		r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(sq)))
+
+		if _, err = tx.ExecContext(ctx, sq); err != nil {
+			r.logger.Error(err)
+			return nil, err
+		}
+
+		ids[i], err = r.insert(ctx, tx, p)
+		if err == profile.ErrUsernameTaken {
+			r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(rq)))
+
+			if _, err = tx.ExecContext(ctx, rq); err != nil {
+				r.logger.Error(err)
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// takenUsernames returns the lowercased usernames of profiles that are in use.
+func (r *mysqlRepository) takenUsernames(ctx context.Context, profiles []profile.Profile) (map[string]bool, error) {
+	usernames := make([]string, len(profiles))
+	for i, p := range profiles {
+		usernames[i] = p.Username
+	}
+	in, args := mysql.In(usernames)
+	q := `SELECT username FROM user WHERE username IN (` + in + `);`
 
 	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
 
-	r.logger.Infoln("p.ID = ", p.ID, "p.Username = ", p.Username, "p.FirstName = ", p.FirstName,
-		"p.LastName = ", p.LastName, "p.Phone = ", p.Phone, "p.Address = ", p.Address,
-		"p.City = ", p.City, "p.School = ", p.School)
+	rows, err := r.client.QueryContext(ctx, q, args...)
+	if err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Usernames compare case-insensitively, like the unique key does.
+	taken := make(map[string]bool)
+	for rows.Next() {
+		var username string
+		if err = rows.Scan(&username); err != nil {
+			r.logger.Error(err)
+			return nil, err
+		}
+		taken[strings.ToLower(username)] = true
+	}
+	if err = rows.Err(); err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+
+	return taken, nil
+}
+
+// insert adds the user, profile and user data rows of p and its outbox
+// message in tx. A taken username fails with ErrUsernameTaken.
+func (r *mysqlRepository) insert(ctx context.Context, tx *sql.Tx, p profile.Profile) (string, error) {
+	uq := `INSERT INTO user (username) VALUES (?);`
+	pq := `INSERT INTO user_profile (user_id, first_name, last_name, phone, address, city) VALUES (?, ?, ?, ?, ?, ?);`
+	dq := `INSERT INTO user_data (user_id, school) VALUES (?, ?);`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(uq)))
 
-	res, err := r.client.ExecContext(ctx, q, p.Username, p.FirstName, p.LastName, p.Phone, p.Address, p.City, p.School)
+	res, err := tx.ExecContext(ctx, uq, p.Username)
 	if err != nil {
+		if mysql.IsDuplicateEntry(err) {
+			return "", profile.ErrUsernameTaken
+		}
 		r.logger.Error(err)
 		return "", err
 	}
@@ -47,17 +181,59 @@ COMMIT;
 		return "", err
 	}
 
-	return strconv.FormatInt(id, 10), nil
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(pq)))
+
+	if _, err = tx.ExecContext(ctx, pq, id, p.FirstName, p.LastName, p.Phone, p.Address, p.City); err != nil {
+		r.logger.Error(err)
+		return "", err
+	}
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(dq)))
+
+	if _, err = tx.ExecContext(ctx, dq, id, p.School); err != nil {
+		r.logger.Error(err)
+		return "", err
+	}
+
+	userID := strconv.FormatInt(id, 10)
+	if err = r.publish(ctx, tx, outbox.ActionCreate, userID); err != nil {
+		return "", err
+	}
+
+	return userID, nil
+}
+
+func (r *mysqlRepository) FindAll(ctx context.Context, opts profile.FindOptions) (p []profile.Profile, err error) {
+	q := `SELECT ` + profileColumns + ` FROM ` + profileTables
+	if !opts.IncludeDeleted {
+		q += ` WHERE ` + notDeleted
+	}
+	q += `;`
+
+	return r.findMany(ctx, q)
 }
 
-func (r *mysqlRepository) FindAll(ctx context.Context) (p []profile.Profile, err error) {
-	q := `SELECT user.username, user_profile.user_id, user_profile.first_name,
-       user_profile.last_name, user_profile.phone, user_profile.address, user_profile.city, user_data.school
-	FROM user JOIN user_profile ON user.id = user_profile.user_id JOIN user_data ON user.id = user_data.user_id;`
+// FindByUserIDs returns the profiles of the users among userIDs, in no
+// particular order.
+func (r *mysqlRepository) FindByUserIDs(ctx context.Context, userIDs []string, opts profile.FindOptions) ([]profile.Profile, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+
+	in, args := mysql.In(userIDs)
+	q := `SELECT ` + profileColumns + ` FROM ` + profileTables + ` WHERE user_profile.user_id IN (` + in + `)`
+	if !opts.IncludeDeleted {
+		q += ` AND ` + notDeleted
+	}
+	q += `;`
+
+	return r.findMany(ctx, q, args...)
+}
 
+func (r *mysqlRepository) findMany(ctx context.Context, q string, args ...interface{}) ([]profile.Profile, error) {
 	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
 
-	rows, err := r.client.QueryContext(ctx, q)
+	rows, err := r.client.QueryContext(ctx, q, args...)
 	if err != nil {
 		r.logger.Error(err)
 		return nil, err
@@ -69,7 +245,7 @@ func (r *mysqlRepository) FindAll(ctx context.Context) (p []profile.Profile, err
 	for rows.Next() {
 		var up profile.Profile
 
-		err = rows.Scan(&up.Username, &up.ID, &up.FirstName, &up.LastName, &up.Phone, &up.Address, &up.City, &up.School)
+		err := rows.Scan(&up.Username, &up.ID, &up.FirstName, &up.LastName, &up.Phone, &up.Address, &up.City, &up.School, &up.Version, &up.DeletedAt)
 		if err != nil {
 			r.logger.Error(err)
 			return nil, err
@@ -86,18 +262,22 @@ func (r *mysqlRepository) FindAll(ctx context.Context) (p []profile.Profile, err
 	return profiles, nil
 }
 
-func (r *mysqlRepository) FindOne(ctx context.Context, Username string) (profile.Profile, error) {
-	q := `SELECT user.username, user_profile.user_id, user_profile.first_name, user_profile.last_name,
-       user_profile.phone, user_profile.address, user_profile.city, user_data.school
-	FROM user JOIN user_profile ON user.id = user_profile.user_id JOIN user_data ON user.id = user_data.user_id WHERE user.username = ?;`
+func (r *mysqlRepository) FindOne(ctx context.Context, username string, opts profile.FindOptions) (profile.Profile, error) {
+	q := `SELECT ` + profileColumns + ` FROM ` + profileTables + ` WHERE user.username = ?`
+	if !opts.IncludeDeleted {
+		q += ` AND ` + notDeleted
+	}
+	q += `;`
 
 	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
 
 	var up profile.Profile
 
-	err := r.client.QueryRowContext(ctx, q, Username).Scan(&up.Username, &up.ID, &up.FirstName, &up.LastName, &up.Phone, &up.Address, &up.City, &up.School)
+	err := r.client.QueryRowContext(ctx, q, username).Scan(&up.Username, &up.ID, &up.FirstName, &up.LastName, &up.Phone, &up.Address, &up.City, &up.School, &up.Version, &up.DeletedAt)
 	if err != nil {
-		r.logger.Error(err)
+		if err != sql.ErrNoRows {
+			r.logger.Error(err)
+		}
 		return profile.Profile{}, err
 	}
 
@@ -105,36 +285,169 @@ func (r *mysqlRepository) FindOne(ctx context.Context, Username string) (profile
 }
 
 func (r *mysqlRepository) Update(ctx context.Context, p profile.Profile) error {
-	q := `UPDATE user_profile SET first_name = ?, last_name = ?, phone = ?, address = ?, city = ? WHERE user_id = ?;`
+	q := `UPDATE ` + profileTables + ` SET user_profile.first_name = ?, user_profile.last_name = ?,
+	user_profile.phone = ?, user_profile.address = ?, user_profile.city = ?, user_profile.version = user_profile.version + 1
+	WHERE user_profile.user_id = ? AND ` + profileVersion + ` = ? AND user_profile.deleted_at IS NULL;`
+
+	err := r.change(ctx, outbox.ActionUpdate, p.ID, q, p.FirstName, p.LastName, p.Phone, p.Address, p.City, p.ID, p.Version)
+	if err == sql.ErrNoRows {
+		return r.notUpdated(ctx, p.ID)
+	}
+	return err
+}
+
+// notUpdated tells a missing profile apart from a version mismatch.
+func (r *mysqlRepository) notUpdated(ctx context.Context, userID string) error {
+	q := `SELECT version FROM user_profile WHERE user_id = ? AND deleted_at IS NULL;`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
 
+	var version int
+	if err := r.client.QueryRowContext(ctx, q, userID).Scan(&version); err != nil {
+		if err != sql.ErrNoRows {
+			r.logger.Error(err)
+		}
+		return err
+	}
+	return profile.ErrConflict
+}
+
+func (r *mysqlRepository) Delete(ctx context.Context, userID string, version int) error {
+	q := `UPDATE ` + profileTables + ` SET user_profile.deleted_at = ?
+	WHERE user_profile.user_id = ? AND ` + profileVersion + ` = ? AND user_profile.deleted_at IS NULL;`
+
+	err := r.change(ctx, outbox.ActionDelete, userID, q, time.Now().UTC().Truncate(time.Second), userID, version)
+	if err == sql.ErrNoRows {
+		return r.notUpdated(ctx, userID)
+	}
+	return err
+}
+
+func (r *mysqlRepository) Restore(ctx context.Context, userID string) error {
+	q := `UPDATE user_profile SET deleted_at = NULL WHERE user_id = ? AND deleted_at IS NOT NULL;`
+
+	return r.change(ctx, outbox.ActionRestore, userID, q, userID)
+}
+
+// change runs the statement q of an action on the profile of userID together
+// with its outbox message. It reports sql.ErrNoRows when q matched nothing.
+func (r *mysqlRepository) change(ctx context.Context, action, userID, q string, args ...interface{}) error {
 	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
 
-	_, err := r.client.ExecContext(ctx, q, p.FirstName, p.LastName, p.Phone, p.Address, p.City, p.ID)
+	tx, err := r.client.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error(err)
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, q, args...)
 	if err != nil {
 		r.logger.Error(err)
 		return err
 	}
+	if err = requireAffected(res); err != nil {
+		return err
+	}
+
+	if err = r.publish(ctx, tx, action, userID); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.logger.Error(err)
+		return err
+	}
 
 	return nil
 }
 
-func (r *mysqlRepository) Delete(ctx context.Context, ID string) error {
-	q := `DELETE FROM user_profile WHERE user_id = ?;`
+// publish adds the message of a change to the outbox in tx. The payload is
+// the profile as tx sees it, none for deletions.
+func (r *mysqlRepository) publish(ctx context.Context, tx *sql.Tx, action, userID string) error {
+	var payload interface{}
+	if action != outbox.ActionDelete {
+		q := `SELECT ` + profileColumns + ` FROM ` + profileTables + ` WHERE user_profile.user_id = ?;`
 
-	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+		r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
 
-	_, err := r.client.ExecContext(ctx, q, ID)
+		var up profile.Profile
+		err := tx.QueryRowContext(ctx, q, userID).Scan(&up.Username, &up.ID, &up.FirstName, &up.LastName, &up.Phone, &up.Address, &up.City, &up.School, &up.Version, &up.DeletedAt)
+		if err != nil {
+			r.logger.Error(err)
+			return err
+		}
+		payload = up
+	}
+
+	m, err := outbox.NewMessage(ctx, outbox.AggregateProfile, userID, action, payload)
 	if err != nil {
 		r.logger.Error(err)
 		return err
 	}
+	return r.outbox.Add(ctx, tx, m)
+}
+
+// PublishChange adds the message of a change of the profile of userID, made
+// through its user in tx, to the outbox. It does nothing when the user has no
+// profile or it was deleted on its own.
+func PublishChange(ctx context.Context, tx *sql.Tx, ob outbox.Repository, logger *logging.Logger, action, userID string) error {
+	q := `SELECT ` + profileColumns + ` FROM ` + profileTables + ` WHERE user_profile.user_id = ? AND user_profile.deleted_at IS NULL;`
+
+	logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
 
+	var up profile.Profile
+	err := tx.QueryRowContext(ctx, q, userID).Scan(&up.Username, &up.ID, &up.FirstName, &up.LastName, &up.Phone, &up.Address, &up.City, &up.School, &up.Version, &up.DeletedAt)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+
+	var payload interface{}
+	if action != outbox.ActionDelete {
+		payload = up
+	}
+	m, err := outbox.NewMessage(ctx, outbox.AggregateProfile, userID, action, payload)
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+	return ob.Add(ctx, tx, m)
+}
+
+func (r *mysqlRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	q := `DELETE FROM user_profile WHERE deleted_at IS NOT NULL AND deleted_at < ?;`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	res, err := r.client.ExecContext(ctx, q, before)
+	if err != nil {
+		r.logger.Error(err)
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// requireAffected reports sql.ErrNoRows when a statement matched nothing.
+func requireAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
 	return nil
 }
 
-func NewMySQLRepository(client mysql.Client, logger *logging.Logger) profile.Repository {
+func NewMySQLRepository(client mysql.Client, outbox outbox.Repository, logger *logging.Logger) profile.Repository {
 	return &mysqlRepository{
 		client: client,
+		outbox: outbox,
 		logger: logger,
 	}
 }
@@ -0,0 +1,168 @@
+package profile
+
+import (
+	"awesome-clean-arch/internal/auth"
+	"awesome-clean-arch/internal/grpcapi"
+	apiv1 "awesome-clean-arch/pkg/api/v1"
+	"awesome-clean-arch/pkg/logging"
+	"awesome-clean-arch/pkg/validate"
+	"context"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+var _ grpcapi.Service = &grpcService{}
+
+type grpcService struct {
+	apiv1.UnimplementedProfileServiceServer
+	logger     *logging.Logger
+	repository Repository
+}
+
+func NewGRPCService(logger *logging.Logger, repository Repository) grpcapi.Service {
+	return &grpcService{
+		logger:     logger,
+		repository: repository,
+	}
+}
+
+func (s *grpcService) Register(server *grpc.Server) {
+	apiv1.RegisterProfileServiceServer(server, s)
+}
+
+func (s *grpcService) Scopes() map[string]string {
+	desc := apiv1.ProfileService_ServiceDesc
+	return map[string]string{
+		grpcapi.FullMethod(desc, "CreateProfile"): auth.ScopeProfilesWrite,
+		grpcapi.FullMethod(desc, "ListProfiles"):  auth.ScopeProfilesRead,
+		grpcapi.FullMethod(desc, "GetProfile"):    auth.ScopeProfilesRead,
+		grpcapi.FullMethod(desc, "UpdateProfile"): auth.ScopeProfilesWrite,
+		grpcapi.FullMethod(desc, "DeleteProfile"): auth.ScopeProfilesWrite,
+	}
+}
+
+func (s *grpcService) CreateProfile(ctx context.Context, req *apiv1.CreateProfileRequest) (*apiv1.CreateProfileResponse, error) {
+	profile := profileFromProto(req.GetProfile())
+	if err := validate.Struct(profile); err != nil {
+		return nil, grpcapi.ValidationError(err)
+	}
+
+	id, err := s.repository.Create(ctx, profile)
+	if errors.Is(err, ErrUsernameTaken) {
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	}
+	if err != nil {
+		return nil, grpcapi.Error(err, "")
+	}
+	return &apiv1.CreateProfileResponse{Id: id}, nil
+}
+
+func (s *grpcService) ListProfiles(ctx context.Context, req *apiv1.ListProfilesRequest) (*apiv1.ListProfilesResponse, error) {
+	if req.GetIncludeDeleted() && !auth.IsAdmin(ctx) {
+		return nil, status.Error(codes.PermissionDenied, "include_deleted requires scope "+auth.ScopeAuthAdmin)
+	}
+
+	all, err := s.repository.FindAll(ctx, FindOptions{IncludeDeleted: req.GetIncludeDeleted()})
+	if err != nil {
+		return nil, grpcapi.Error(err, "")
+	}
+
+	profiles := make([]*apiv1.Profile, len(all))
+	for i, p := range all {
+		profiles[i] = profileToProto(p)
+	}
+	return &apiv1.ListProfilesResponse{Profiles: profiles}, nil
+}
+
+func (s *grpcService) GetProfile(ctx context.Context, req *apiv1.GetProfileRequest) (*apiv1.Profile, error) {
+	if req.GetIncludeDeleted() && !auth.IsAdmin(ctx) {
+		return nil, status.Error(codes.PermissionDenied, "include_deleted requires scope "+auth.ScopeAuthAdmin)
+	}
+
+	profile, err := s.repository.FindOne(ctx, req.GetUsername(), FindOptions{IncludeDeleted: req.GetIncludeDeleted()})
+	if err != nil {
+		return nil, grpcapi.Error(err, "User not found")
+	}
+	return profileToProto(profile), nil
+}
+
+// UpdateProfile replaces the personal fields like PUT /profile/:username.
+func (s *grpcService) UpdateProfile(ctx context.Context, req *apiv1.UpdateProfileRequest) (*apiv1.Profile, error) {
+	update := profileFromProto(req.GetProfile())
+	if update.Version == 0 {
+		return nil, grpcapi.VersionRequired()
+	}
+
+	current, err := s.repository.FindOne(ctx, update.Username, FindOptions{})
+	if err != nil {
+		return nil, grpcapi.Error(err, "User not found")
+	}
+
+	update.ID, update.School = current.ID, current.School
+	if err = validate.Struct(update); err != nil {
+		return nil, grpcapi.ValidationError(err)
+	}
+
+	if err = s.repository.Update(ctx, update); err != nil {
+		if errors.Is(err, ErrConflict) {
+			return nil, grpcapi.Conflict(err)
+		}
+		return nil, grpcapi.Error(err, "User not found")
+	}
+
+	profile, err := s.repository.FindOne(ctx, update.Username, FindOptions{})
+	if err != nil {
+		return nil, grpcapi.Error(err, "User not found")
+	}
+	return profileToProto(profile), nil
+}
+
+func (s *grpcService) DeleteProfile(ctx context.Context, req *apiv1.DeleteProfileRequest) (*emptypb.Empty, error) {
+	profile, err := s.repository.FindOne(ctx, req.GetUsername(), FindOptions{})
+	if err != nil {
+		return nil, grpcapi.Error(err, "User not found")
+	}
+	if req.GetVersion() != 0 && int(req.GetVersion()) != profile.Version {
+		return nil, grpcapi.Conflict(ErrConflict)
+	}
+
+	if err = s.repository.Delete(ctx, profile.ID, profile.Version); err != nil {
+		if errors.Is(err, ErrConflict) {
+			return nil, grpcapi.Conflict(err)
+		}
+		return nil, grpcapi.Error(err, "User not found")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func profileToProto(p Profile) *apiv1.Profile {
+	return &apiv1.Profile{
+		UserId:    p.ID,
+		Username:  p.Username,
+		Firstname: p.FirstName,
+		Lastname:  p.LastName,
+		Phone:     p.Phone,
+		Address:   p.Address,
+		City:      p.City,
+		School:    p.School,
+		Version:   int32(p.Version),
+		DeletedAt: grpcapi.Timestamp(p.DeletedAt),
+	}
+}
+
+func profileFromProto(p *apiv1.Profile) Profile {
+	return Profile{
+		ID:        p.GetUserId(),
+		Username:  p.GetUsername(),
+		FirstName: p.GetFirstname(),
+		LastName:  p.GetLastname(),
+		Phone:     p.GetPhone(),
+		Address:   p.GetAddress(),
+		City:      p.GetCity(),
+		School:    p.GetSchool(),
+		Version:   int(p.GetVersion()),
+	}
+}
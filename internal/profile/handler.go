@@ -1,20 +1,61 @@
 package profile
 
 import (
+	"awesome-clean-arch/internal/auth"
 	"awesome-clean-arch/internal/handlers"
 	"awesome-clean-arch/pkg/logging"
-	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"github.com/julienschmidt/httprouter"
+	"io"
 	"net/http"
 )
 
-const (
-	profilesURL      = "/profile"
-	profileURL       = "/profile/:username"
-	createProfileURL = "/profile/create"
-)
+// representation is the JSON shape and routes of profiles in one API version.
+type representation struct {
+	listURL   string
+	itemURL   string
+	createURL string
+	importURL string
+	encode    func(p Profile) interface{}
+	// decode reads body over p, fields missing from body keep their value.
+	decode func(body io.Reader, p Profile) (Profile, error)
+	// model is a sample of the encoded profile for the API docs.
+	model interface{}
+	list  interface{}
+}
+
+var v1 = representation{
+	listURL:   "/profile",
+	itemURL:   "/profile/:username",
+	createURL: "/profile/create",
+	importURL: "/profile/import",
+	encode:    func(p Profile) interface{} { return p },
+	decode: func(body io.Reader, p Profile) (Profile, error) {
+		err := json.NewDecoder(body).Decode(&p)
+		return p, err
+	},
+	model: Profile{},
+	list:  []Profile{},
+}
+
+// v2 nests the name and contact fields and creates profiles with a POST on
+// the collection.
+var v2 = representation{
+	listURL:   "/profiles",
+	itemURL:   "/profiles/:username",
+	createURL: "/profiles",
+	importURL: "/profiles/import",
+	encode:    func(p Profile) interface{} { return p.V2() },
+	decode: func(body io.Reader, p Profile) (Profile, error) {
+		v := p.V2()
+		err := json.NewDecoder(body).Decode(&v)
+		return v.Profile(), err
+	},
+	model: ProfileV2{},
+	list:  []ProfileV2{},
+}
 
 var _ handlers.Handler = &handler{}
 
@@ -25,30 +66,91 @@ type ErrorResponse struct {
 type handler struct {
 	logger     *logging.Logger
 	repository Repository
+	repr       representation
 }
 
 func NewHandler(logger *logging.Logger, repository Repository) handlers.Handler {
 	return &handler{
 		logger:     logger,
 		repository: repository,
+		repr:       v1,
+	}
+}
+
+// NewHandlerV2 serves the profiles of repository in the v2 representation.
+func NewHandlerV2(logger *logging.Logger, repository Repository) handlers.Handler {
+	return &handler{
+		logger:     logger,
+		repository: repository,
+		repr:       v2,
 	}
 }
 
-func (h *handler) Register(router *httprouter.Router) {
-	router.GET(profilesURL, h.GetProfilesList)
-	router.GET(profileURL, h.GetProfile)
-	router.POST(createProfileURL, h.CreateProfile)
+func (h *handler) Middleware() []handlers.Middleware {
+	return nil
+}
+
+func (h *handler) Register(router handlers.Router) {
+	router.GET(h.repr.listURL, h.GetProfilesList, auth.RequireScope(auth.ScopeProfilesRead))
+	router.GET(h.repr.itemURL, h.GetProfile, auth.RequireScope(auth.ScopeProfilesRead))
+	router.POST(h.repr.createURL, h.CreateProfile, auth.RequireScope(auth.ScopeProfilesWrite))
+	// Imports are streamed and can take long, so they are neither buffered for
+	// replay nor timed out, and ImportProfiles bounds their body itself.
+	router.With(handlers.NoReplay|handlers.NoTimeout|handlers.NoBodyLimit).
+		POST(h.repr.importURL, h.ImportProfiles, auth.RequireScope(auth.ScopeProfilesWrite))
+	router.PUT(h.repr.itemURL, h.UpdateProfile, auth.RequireScope(auth.ScopeProfilesWrite))
+	router.PATCH(h.repr.itemURL, h.PatchProfile, auth.RequireScope(auth.ScopeProfilesWrite))
+	router.DELETE(h.repr.itemURL, h.DeleteProfile, auth.RequireScope(auth.ScopeProfilesWrite))
+}
+
+func (h *handler) Docs() []handlers.Doc {
+	tags := []string{"profiles"}
+	profilesURL, profileURL, createProfileURL := h.repr.listURL, h.repr.itemURL, h.repr.createURL
+	model, list := h.repr.model, h.repr.list
+	return []handlers.Doc{
+		{Method: http.MethodGet, Path: profilesURL, Summary: "List profiles", Tags: tags, Scope: auth.ScopeProfilesRead,
+			Query: []handlers.Param{{Name: "include_deleted", Description: "Also return soft-deleted rows, admin only"}}, Response: list},
+		{Method: http.MethodGet, Path: profileURL, Summary: "Get a profile by username", Tags: tags, Scope: auth.ScopeProfilesRead,
+			Query: []handlers.Param{{Name: "include_deleted", Description: "Also return soft-deleted rows, admin only"}}, Response: model, Conditional: true},
+		{Method: http.MethodPost, Path: createProfileURL, Summary: "Create a user with its profile", Tags: tags,
+			Scope: auth.ScopeProfilesWrite, Request: model, Response: map[string]string{}, Status: http.StatusCreated, Idempotent: true},
+		{Method: http.MethodPost, Path: h.repr.importURL, Summary: "Create users with their profiles from CSV or NDJSON", Tags: tags,
+			Description: "The body is text/csv, with a header naming the columns among username, firstname, lastname, " +
+				"phone, address, city and school, or application/x-ndjson with one profile per line. Rows are " +
+				"validated one by one and created in batches, the report gives the outcome of every row. " +
+				"The body is limited to 64 MiB.",
+			Scope: auth.ScopeProfilesWrite, Response: ImportReport{},
+			Query: []handlers.Param{{Name: "dry_run", Description: "Validate and report without storing anything"}}},
+		{Method: http.MethodPut, Path: profileURL, Summary: "Replace the personal fields of a profile", Tags: tags,
+			Scope: auth.ScopeProfilesWrite, Request: model, Response: model, Conditional: true},
+		{Method: http.MethodPatch, Path: profileURL, Summary: "Change some personal fields of a profile", Tags: tags,
+			Scope: auth.ScopeProfilesWrite, Request: model, Response: model, Conditional: true},
+		{Method: http.MethodDelete, Path: profileURL, Summary: "Soft-delete a profile", Tags: tags, Scope: auth.ScopeProfilesWrite,
+			Status: http.StatusNoContent, Conditional: true},
+	}
 }
 
 func (h *handler) GetProfilesList(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	w.Header().Set("Content-Type", "application/json")
-	all, err := h.repository.FindAll(context.TODO())
+
+	includeDeleted, ok := auth.IncludeDeleted(r)
+	if !ok {
+		handlers.WriteError(w, http.StatusForbidden, "include_deleted requires scope "+auth.ScopeAuthAdmin)
+		return
+	}
+
+	all, err := h.repository.FindAll(r.Context(), FindOptions{IncludeDeleted: includeDeleted})
 	if err != nil {
 		w.WriteHeader(400)
 		return
 	}
 
-	allBytes, err := json.Marshal(all)
+	encoded := make([]interface{}, len(all))
+	for i, p := range all {
+		encoded[i] = h.repr.encode(p)
+	}
+
+	allBytes, err := json.Marshal(encoded)
 	if err != nil {
 		return
 	}
@@ -64,7 +166,13 @@ func (h *handler) GetProfile(w http.ResponseWriter, r *http.Request, params http
 
 	username := params.ByName("username")
 
-	profile, err := h.repository.FindOne(context.TODO(), username)
+	includeDeleted, ok := auth.IncludeDeleted(r)
+	if !ok {
+		handlers.WriteError(w, http.StatusForbidden, "include_deleted requires scope "+auth.ScopeAuthAdmin)
+		return
+	}
+
+	profile, err := h.repository.FindOne(r.Context(), username, FindOptions{IncludeDeleted: includeDeleted})
 	if err != nil {
 		if err == sql.ErrNoRows {
 			w.WriteHeader(http.StatusNotFound)
@@ -79,7 +187,12 @@ func (h *handler) GetProfile(w http.ResponseWriter, r *http.Request, params http
 		return
 	}
 
-	profileJSON, err := json.Marshal(profile)
+	etag := handlers.ETag(profile.Version)
+	if handlers.NotModified(w, r, etag) {
+		return
+	}
+
+	profileJSON, err := json.Marshal(h.repr.encode(profile))
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		errorResponse := ErrorResponse{Error: err.Error()}
@@ -87,15 +200,103 @@ func (h *handler) GetProfile(w http.ResponseWriter, r *http.Request, params http
 		return
 	}
 
+	w.Header().Set("ETag", etag)
 	w.WriteHeader(http.StatusOK)
 	w.Write(profileJSON)
 }
 
+// UpdateProfile replaces the personal fields of a profile. The username and
+// school are managed through /user and /user-data.
+func (h *handler) UpdateProfile(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	update, err := h.repr.decode(r.Body, Profile{})
+	if err != nil {
+		handlers.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	version, ok := handlers.ExpectedVersion(w, r, update.Version)
+	if !ok {
+		return
+	}
+
+	current, err := h.repository.FindOne(r.Context(), params.ByName("username"), FindOptions{})
+	if err != nil {
+		h.writeUpdateError(w, r, err)
+		return
+	}
+
+	update.ID, update.Username, update.School, update.Version = current.ID, current.Username, current.School, version
+	h.update(w, r, update)
+}
+
+// PatchProfile changes only the fields present in the body. Without
+// If-Match the write is checked against the version read here.
+func (h *handler) PatchProfile(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	current, err := h.repository.FindOne(r.Context(), params.ByName("username"), FindOptions{})
+	if err != nil {
+		h.writeUpdateError(w, r, err)
+		return
+	}
+
+	version, ok, err := handlers.IfMatch(r)
+	if err != nil {
+		handlers.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !ok {
+		version = current.Version
+	}
+
+	update, err := h.repr.decode(r.Body, current)
+	if err != nil {
+		handlers.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	update.ID, update.Username, update.School, update.Version = current.ID, current.Username, current.School, version
+	h.update(w, r, update)
+}
+
+func (h *handler) update(w http.ResponseWriter, r *http.Request, update Profile) {
+	if !handlers.Validate(w, r, h.repr.encode(update)) {
+		return
+	}
+
+	if err := h.repository.Update(r.Context(), update); err != nil {
+		h.writeUpdateError(w, r, err)
+		return
+	}
+
+	profile, err := h.repository.FindOne(r.Context(), update.Username, FindOptions{})
+	if err != nil {
+		handlers.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("ETag", handlers.ETag(profile.Version))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.repr.encode(profile))
+}
+
+func (h *handler) writeUpdateError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case err == sql.ErrNoRows:
+		handlers.WriteError(w, http.StatusNotFound, "User not found")
+	case errors.Is(err, ErrConflict):
+		handlers.WriteConflict(w, r, err.Error())
+	default:
+		handlers.WriteError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
 func (h *handler) CreateProfile(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	w.Header().Set("Content-Type", "application/json")
 
-	var profile Profile
-	err := json.NewDecoder(r.Body).Decode(&profile)
+	profile, err := h.repr.decode(r.Body, Profile{})
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		errorResponse := ErrorResponse{Error: err.Error()}
@@ -103,7 +304,15 @@ func (h *handler) CreateProfile(w http.ResponseWriter, r *http.Request, params h
 		return
 	}
 
-	id, err := h.repository.Create(context.TODO(), profile)
+	if !handlers.Validate(w, r, h.repr.encode(profile)) {
+		return
+	}
+
+	id, err := h.repository.Create(r.Context(), profile)
+	if errors.Is(err, ErrUsernameTaken) {
+		handlers.WriteError(w, http.StatusConflict, err.Error())
+		return
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		errorResponse := ErrorResponse{Error: err.Error()}
@@ -115,3 +324,26 @@ func (h *handler) CreateProfile(w http.ResponseWriter, r *http.Request, params h
 	response := map[string]string{"id": id}
 	json.NewEncoder(w).Encode(response)
 }
+
+func (h *handler) DeleteProfile(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	profile, err := h.repository.FindOne(r.Context(), params.ByName("username"), FindOptions{})
+	if err == nil {
+		if !handlers.CheckIfMatch(w, r, profile.Version) {
+			return
+		}
+		err = h.repository.Delete(r.Context(), profile.ID, profile.Version)
+	}
+	if err != nil {
+		switch {
+		case err == sql.ErrNoRows:
+			handlers.WriteError(w, http.StatusNotFound, "User not found")
+		case errors.Is(err, ErrConflict):
+			handlers.WriteConflict(w, r, err.Error())
+		default:
+			handlers.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,268 @@
+package profile
+
+import (
+	"awesome-clean-arch/internal/handlers"
+	"awesome-clean-arch/pkg/validate"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/julienschmidt/httprouter"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// importBatchSize is the number of profiles created per transaction.
+const importBatchSize = 100
+
+// importMaxBodySize bounds an import, which the global body limit does not.
+const importMaxBodySize = 64 << 20
+
+// Outcomes of an imported row.
+const (
+	ImportCreated = "created"
+	ImportSkipped = "skipped"
+	ImportFailed  = "failed"
+)
+
+type ImportRow struct {
+	// Line is the line of the row in the body, starting at 1.
+	Line     int    `json:"line"`
+	Username string `json:"username,omitempty"`
+	Status   string `json:"status"`
+	// ID is the id of the created user, empty in a dry run.
+	ID     string                `json:"user_id,omitempty"`
+	Reason string                `json:"reason,omitempty"`
+	Fields []validate.FieldError `json:"fields,omitempty"`
+}
+
+type ImportReport struct {
+	DryRun  bool        `json:"dry_run"`
+	Created int         `json:"created"`
+	Skipped int         `json:"skipped"`
+	Failed  int         `json:"failed"`
+	Rows    []ImportRow `json:"rows"`
+}
+
+// csvColumns set the field of a profile named by a CSV header.
+var csvColumns = map[string]func(p *Profile, v string){
+	"username":  func(p *Profile, v string) { p.Username = v },
+	"firstname": func(p *Profile, v string) { p.FirstName = v },
+	"lastname":  func(p *Profile, v string) { p.LastName = v },
+	"phone":     func(p *Profile, v string) { p.Phone = v },
+	"address":   func(p *Profile, v string) { p.Address = v },
+	"city":      func(p *Profile, v string) { p.City = v },
+	"school":    func(p *Profile, v string) { p.School = v },
+}
+
+// row is a row of an import, err is set when it could not be read.
+type row struct {
+	line    int
+	profile Profile
+	err     error
+}
+
+// rowReader reads the rows of an import one at a time. next returns io.EOF
+// after the last row and any other error when the rest of the body is
+// unreadable.
+type rowReader interface {
+	next() (row, error)
+}
+
+type csvReader struct {
+	reader  *csv.Reader
+	columns []func(p *Profile, v string)
+}
+
+func newCSVReader(body io.Reader) (*csvReader, error) {
+	reader := csv.NewReader(body)
+	reader.ReuseRecord = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("the CSV header is missing")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([]func(p *Profile, v string), len(header))
+	seen := make(map[string]bool)
+	for i, name := range header {
+		// Spreadsheets may start the file with a byte order mark.
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		set, ok := csvColumns[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown CSV column %q", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate CSV column %q", name)
+		}
+		seen[name], columns[i] = true, set
+	}
+	if !seen["username"] {
+		return nil, errors.New("the CSV column username is required")
+	}
+
+	return &csvReader{reader: reader, columns: columns}, nil
+}
+
+func (r *csvReader) next() (row, error) {
+	record, err := r.reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		// A row with the wrong number of fields leaves the reader usable.
+		if errors.As(err, &parseErr) && errors.Is(parseErr.Err, csv.ErrFieldCount) {
+			return row{line: parseErr.StartLine, err: parseErr.Err}, nil
+		}
+		return row{}, err
+	}
+
+	var p Profile
+	for i, v := range record {
+		r.columns[i](&p, v)
+	}
+	line, _ := r.reader.FieldPos(0)
+	return row{line: line, profile: p}, nil
+}
+
+type ndjsonReader struct {
+	reader *bufio.Reader
+	decode func(body io.Reader, p Profile) (Profile, error)
+	line   int
+}
+
+func (r *ndjsonReader) next() (row, error) {
+	for {
+		data, err := r.reader.ReadBytes('\n')
+		if err != nil && (err != io.EOF || len(data) == 0) {
+			return row{}, err
+		}
+		r.line++
+
+		data = bytes.TrimSpace(data)
+		if len(data) == 0 {
+			continue
+		}
+
+		p, err := r.decode(bytes.NewReader(data), Profile{})
+		return row{line: r.line, profile: p, err: err}, nil
+	}
+}
+
+// ImportProfiles creates the profiles of a CSV or NDJSON body row by row, in
+// batched transactions, and reports the outcome of every row. Invalid rows
+// fail, rows whose username is taken or repeated are skipped, the others are
+// created. With dry_run=true nothing is stored.
+func (h *handler) ImportProfiles(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	if r.ContentLength > importMaxBodySize {
+		handlers.WriteError(w, http.StatusRequestEntityTooLarge, "request body too large")
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, importMaxBodySize)
+
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var rows rowReader
+	switch mediaType {
+	case "text/csv":
+		reader, err := newCSVReader(r.Body)
+		if err != nil {
+			handlers.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		rows = reader
+	case "application/x-ndjson", "application/ndjson":
+		rows = &ndjsonReader{reader: bufio.NewReader(r.Body), decode: h.repr.decode}
+	default:
+		handlers.WriteError(w, http.StatusUnsupportedMediaType, "the body must be text/csv or application/x-ndjson")
+		return
+	}
+
+	report := ImportReport{DryRun: dryRun, Rows: make([]ImportRow, 0)}
+	seen := make(map[string]int)
+	var batch []Profile
+	var batchRows []int
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ids, err := h.repository.CreateBatch(r.Context(), batch, dryRun)
+		for i, idx := range batchRows {
+			result := &report.Rows[idx]
+			switch {
+			case err != nil:
+				result.Status, result.Reason = ImportFailed, err.Error()
+			case ids[i] == "":
+				result.Status, result.Reason = ImportSkipped, ErrUsernameTaken.Error()
+			default:
+				result.Status = ImportCreated
+				if !dryRun {
+					result.ID = ids[i]
+				}
+			}
+		}
+		batch, batchRows = batch[:0], batchRows[:0]
+	}
+
+	for {
+		next, err := rows.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			report.Rows = append(report.Rows, ImportRow{Status: ImportFailed,
+				Reason: "unreadable body, the remaining rows were not imported: " + err.Error()})
+			break
+		}
+
+		result := ImportRow{Line: next.line, Username: next.profile.Username}
+		username := strings.ToLower(next.profile.Username)
+		if next.err != nil {
+			result.Status, result.Reason = ImportFailed, next.err.Error()
+		} else if err = validate.Struct(h.validated(next.profile, mediaType)); err != nil {
+			result.Status, result.Reason, result.Fields = ImportFailed, "validation failed", validate.Fields(err)
+		} else if first, ok := seen[username]; ok {
+			result.Status, result.Reason = ImportSkipped, fmt.Sprintf("username repeats line %d", first)
+		} else {
+			seen[username] = next.line
+			batch, batchRows = append(batch, next.profile), append(batchRows, len(report.Rows))
+		}
+		report.Rows = append(report.Rows, result)
+
+		if len(batch) == importBatchSize {
+			flush()
+		}
+	}
+	flush()
+
+	for _, result := range report.Rows {
+		switch result.Status {
+		case ImportCreated:
+			report.Created++
+		case ImportSkipped:
+			report.Skipped++
+		default:
+			report.Failed++
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+// validated is the value a row is validated as. CSV rows use the flat field
+// names of their columns whatever the API version.
+func (h *handler) validated(p Profile, mediaType string) interface{} {
+	if mediaType == "text/csv" {
+		return p
+	}
+	return h.repr.encode(p)
+}
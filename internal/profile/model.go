@@ -1,12 +1,78 @@
 package profile
 
+import (
+	"errors"
+	"time"
+)
+
+// ErrConflict is returned by Update and Delete when the stored version
+// differs from the one the caller read.
+var ErrConflict = errors.New("profile was modified concurrently")
+
+var ErrUsernameTaken = errors.New("username is already taken")
+
 type Profile struct {
 	ID        string `json:"user_id"`
-	Username  string `json:"username"`
-	FirstName string `json:"firstname"`
-	LastName  string `json:"lastname"`
-	Phone     string `json:"phone"`
-	Address   string `json:"address"`
-	City      string `json:"city"`
-	School    string `json:"school"`
+	Username  string `json:"username" validate:"required,max=64"`
+	FirstName string `json:"firstname" validate:"required,max=32"`
+	LastName  string `json:"lastname" validate:"max=64"`
+	Phone     string `json:"phone" validate:"max=64"`
+	Address   string `json:"address" validate:"max=64"`
+	City      string `json:"city" validate:"max=64"`
+	School    string `json:"school" validate:"max=32"`
+	// Version changes with the user, its profile or its user data, it is
+	// the sum of their versions.
+	Version int `json:"version"`
+	// DeletedAt is set when the profile or its user is soft-deleted.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// ProfileV2 is the representation of a profile served by the v2 API, it
+// groups the name and contact fields.
+type ProfileV2 struct {
+	ID        string     `json:"user_id"`
+	Username  string     `json:"username" validate:"required,max=64"`
+	Name      Name       `json:"name"`
+	Contact   Contact    `json:"contact"`
+	School    string     `json:"school" validate:"max=32"`
+	Version   int        `json:"version"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+type Name struct {
+	First string `json:"first" validate:"required,max=32"`
+	Last  string `json:"last" validate:"max=64"`
+}
+
+type Contact struct {
+	Phone   string `json:"phone" validate:"max=64"`
+	Address string `json:"address" validate:"max=64"`
+	City    string `json:"city" validate:"max=64"`
+}
+
+func (p Profile) V2() ProfileV2 {
+	return ProfileV2{
+		ID:        p.ID,
+		Username:  p.Username,
+		Name:      Name{First: p.FirstName, Last: p.LastName},
+		Contact:   Contact{Phone: p.Phone, Address: p.Address, City: p.City},
+		School:    p.School,
+		Version:   p.Version,
+		DeletedAt: p.DeletedAt,
+	}
+}
+
+func (p ProfileV2) Profile() Profile {
+	return Profile{
+		ID:        p.ID,
+		Username:  p.Username,
+		FirstName: p.Name.First,
+		LastName:  p.Name.Last,
+		Phone:     p.Contact.Phone,
+		Address:   p.Contact.Address,
+		City:      p.Contact.City,
+		School:    p.School,
+		Version:   p.Version,
+		DeletedAt: p.DeletedAt,
+	}
 }
@@ -1,11 +1,34 @@
 package profile
 
-import "context"
+import (
+	"context"
+	"time"
+)
+
+// FindOptions tunes lookups. Profiles that are soft-deleted, or whose user
+// is, are skipped unless IncludeDeleted is set.
+type FindOptions struct {
+	IncludeDeleted bool
+}
+
+// DryRunID stands for the id of a profile a dry run would create, no user
+// has it.
+const DryRunID = "0"
 
 type Repository interface {
 	Create(ctx context.Context, profile Profile) (string, error)
-	FindAll(ctx context.Context) (p []Profile, err error)
-	FindOne(ctx context.Context, userID string) (Profile, error)
+	// CreateBatch creates profiles like Create in one transaction and returns
+	// their ids in order. Profiles whose username is taken are skipped with an
+	// empty id. With dryRun nothing is written, the profiles that would be
+	// created get DryRunID.
+	CreateBatch(ctx context.Context, profiles []Profile, dryRun bool) ([]string, error)
+	FindAll(ctx context.Context, opts FindOptions) (p []Profile, err error)
+	FindOne(ctx context.Context, username string, opts FindOptions) (Profile, error)
+	FindByUserIDs(ctx context.Context, userIDs []string, opts FindOptions) ([]Profile, error)
+	// Update and Delete succeed only while the stored version equals the
+	// given one, otherwise they return ErrConflict. Update bumps the version.
 	Update(ctx context.Context, profile Profile) error
-	Delete(ctx context.Context, userID string) error
+	Delete(ctx context.Context, userID string, version int) error
+	Restore(ctx context.Context, userID string) error
+	Purge(ctx context.Context, before time.Time) (int64, error)
 }
@@ -0,0 +1,67 @@
+package purge
+
+import (
+	"awesome-clean-arch/pkg/logging"
+	"context"
+	"time"
+)
+
+// Purger hard-deletes rows that became obsolete before the given time.
+type Purger interface {
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
+
+type target struct {
+	name   string
+	purger Purger
+}
+
+// Job periodically purges rows older than the retention period, such as
+// soft-deleted users. Targets run in the order they were added.
+type Job struct {
+	retention time.Duration
+	interval  time.Duration
+	logger    *logging.Logger
+	targets   []target
+}
+
+func NewJob(retention, interval time.Duration, logger *logging.Logger) *Job {
+	return &Job{
+		retention: retention,
+		interval:  interval,
+		logger:    logger,
+	}
+}
+
+func (j *Job) Add(name string, purger Purger) {
+	j.targets = append(j.targets, target{name: name, purger: purger})
+}
+
+// Run purges once at start and then every interval until ctx is done.
+func (j *Job) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		j.purge(ctx, time.Now().Add(-j.retention))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *Job) purge(ctx context.Context, before time.Time) {
+	for _, t := range j.targets {
+		n, err := t.purger.Purge(ctx, before)
+		if err != nil {
+			j.logger.Errorf("failed to purge %s: %s", t.name, err)
+			continue
+		}
+		if n > 0 {
+			j.logger.Infof("purged %d %s", n, t.name)
+		}
+	}
+}
@@ -1,20 +1,31 @@
 package mysql_user
 
 import (
+	"awesome-clean-arch/internal/outbox"
+	"awesome-clean-arch/internal/profile/db/mysql"
 	"awesome-clean-arch/internal/user"
 	"awesome-clean-arch/pkg/client/mysql"
 	"awesome-clean-arch/pkg/logging"
 	"context"
+	"database/sql"
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 )
 
+const userColumns = `id, username, version, deleted_at`
+
 type mysqlRepository struct {
 	client mysql.Client
+	outbox outbox.Repository
 	logger *logging.Logger
 }
 
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
 func formatQuery(q string) string {
 	return strings.ReplaceAll(strings.ReplaceAll(q, "\t", ""), "\n", " ")
 }
@@ -24,7 +35,14 @@ func (r *mysqlRepository) Create(ctx context.Context, user user.User) (string, e
 
 	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
 
-	res, err := r.client.ExecContext(ctx, q, user.Username)
+	tx, err := r.client.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error(err)
+		return "", err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, q, user.Username)
 	if err != nil {
 		r.logger.Error(err)
 		return "", err
@@ -36,15 +54,49 @@ func (r *mysqlRepository) Create(ctx context.Context, user user.User) (string, e
 		return "", err
 	}
 
-	return strconv.FormatInt(id, 10), nil
+	ID := strconv.FormatInt(id, 10)
+	if err = r.publish(ctx, tx, outbox.ActionCreate, ID); err != nil {
+		return "", err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.logger.Error(err)
+		return "", err
+	}
+
+	return ID, nil
 }
 
-func (r *mysqlRepository) FindAll(ctx context.Context) (u []user.User, err error) {
-	q := `SELECT id, username FROM user;`
+func (r *mysqlRepository) FindAll(ctx context.Context, opts user.FindOptions) (u []user.User, err error) {
+	q := `SELECT ` + userColumns + ` FROM user`
+	if !opts.IncludeDeleted {
+		q += ` WHERE deleted_at IS NULL`
+	}
+	q += `;`
+
+	return r.findMany(ctx, q)
+}
+
+// FindByIDs returns the users among IDs, in no particular order.
+func (r *mysqlRepository) FindByIDs(ctx context.Context, IDs []string, opts user.FindOptions) ([]user.User, error) {
+	if len(IDs) == 0 {
+		return nil, nil
+	}
 
+	in, args := mysql.In(IDs)
+	q := `SELECT ` + userColumns + ` FROM user WHERE id IN (` + in + `)`
+	if !opts.IncludeDeleted {
+		q += ` AND deleted_at IS NULL`
+	}
+	q += `;`
+
+	return r.findMany(ctx, q, args...)
+}
+
+func (r *mysqlRepository) findMany(ctx context.Context, q string, args ...interface{}) ([]user.User, error) {
 	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
 
-	rows, err := r.client.QueryContext(ctx, q)
+	rows, err := r.client.QueryContext(ctx, q, args...)
 	if err != nil {
 		r.logger.Error(err)
 		return nil, err
@@ -56,7 +108,7 @@ func (r *mysqlRepository) FindAll(ctx context.Context) (u []user.User, err error
 	for rows.Next() {
 		var u user.User
 
-		err = rows.Scan(&u.ID, &u.Username)
+		err := rows.Scan(&u.ID, &u.Username, &u.Version, &u.DeletedAt)
 		if err != nil {
 			r.logger.Error(err)
 			return nil, err
@@ -73,16 +125,26 @@ func (r *mysqlRepository) FindAll(ctx context.Context) (u []user.User, err error
 	return users, nil
 }
 
-func (r *mysqlRepository) FindOne(ctx context.Context, ID string) (user.User, error) {
-	q := `SELECT id, username FROM user WHERE id = ?;`
+func (r *mysqlRepository) FindOne(ctx context.Context, ID string, opts user.FindOptions) (user.User, error) {
+	q := `SELECT ` + userColumns + ` FROM user WHERE id = ?`
+	if !opts.IncludeDeleted {
+		q += ` AND deleted_at IS NULL`
+	}
+	q += `;`
+
+	return r.findOne(ctx, r.client, q, ID)
+}
 
+func (r *mysqlRepository) findOne(ctx context.Context, client queryer, q string, ID string) (user.User, error) {
 	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
 
 	var u user.User
 
-	err := r.client.QueryRowContext(ctx, q, ID).Scan(&u.ID, &u.Username)
+	err := client.QueryRowContext(ctx, q, ID).Scan(&u.ID, &u.Username, &u.Version, &u.DeletedAt)
 	if err != nil {
-		r.logger.Error(err)
+		if err != sql.ErrNoRows {
+			r.logger.Error(err)
+		}
 		return user.User{}, err
 	}
 
@@ -90,36 +152,133 @@ func (r *mysqlRepository) FindOne(ctx context.Context, ID string) (user.User, er
 }
 
 func (r *mysqlRepository) Update(ctx context.Context, user user.User) error {
-	q := `UPDATE user SET username = ? WHERE id = ?;`
+	q := `UPDATE user SET username = ?, version = version + 1 WHERE id = ? AND version = ? AND deleted_at IS NULL;`
+
+	ID := strconv.Itoa(user.ID)
+	err := r.change(ctx, outbox.ActionUpdate, ID, q, user.Username, user.ID, user.Version)
+	if err == sql.ErrNoRows {
+		return r.notUpdated(ctx, ID)
+	}
+	return err
+}
+
+// notUpdated tells a missing user apart from a version mismatch.
+func (r *mysqlRepository) notUpdated(ctx context.Context, ID string) error {
+	if _, err := r.FindOne(ctx, ID, user.FindOptions{}); err != nil {
+		return err
+	}
+	return user.ErrConflict
+}
+
+func (r *mysqlRepository) Delete(ctx context.Context, ID string, version int) error {
+	q := `UPDATE user SET deleted_at = ? WHERE id = ? AND version = ? AND deleted_at IS NULL;`
+
+	err := r.change(ctx, outbox.ActionDelete, ID, q, time.Now().UTC().Truncate(time.Second), ID, version)
+	if err == sql.ErrNoRows {
+		return r.notUpdated(ctx, ID)
+	}
+	return err
+}
 
+// change runs the statement q of an action on the user ID together with its
+// outbox message. It reports sql.ErrNoRows when q matched nothing.
+func (r *mysqlRepository) change(ctx context.Context, action, ID, q string, args ...interface{}) error {
 	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
 
-	_, err := r.client.ExecContext(ctx, q, user.Username, user.ID)
+	tx, err := r.client.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error(err)
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, q, args...)
 	if err != nil {
 		r.logger.Error(err)
 		return err
 	}
+	if err = requireAffected(res); err != nil {
+		return err
+	}
+
+	if err = r.publish(ctx, tx, action, ID); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.logger.Error(err)
+		return err
+	}
 
 	return nil
 }
 
-func (r *mysqlRepository) Delete(ctx context.Context, ID string) error {
-	q := `DELETE FROM user WHERE id = ?;`
+// Restore brings back a soft-deleted user. Its profile comes back with it,
+// unless the profile was deleted on its own.
+func (r *mysqlRepository) Restore(ctx context.Context, ID string) error {
+	q := `UPDATE user SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL;`
+
+	return r.change(ctx, outbox.ActionRestore, ID, q, ID)
+}
+
+func (r *mysqlRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	q := `DELETE FROM user WHERE deleted_at IS NOT NULL AND deleted_at < ?;`
 
 	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
 
-	_, err := r.client.ExecContext(ctx, q, ID)
+	res, err := r.client.ExecContext(ctx, q, before)
+	if err != nil {
+		r.logger.Error(err)
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// publish adds the message of a change to the outbox in tx. The payload is
+// the user as tx sees it, none for deletions. Deleting and restoring a user
+// does the same to its profile, which gets its own message.
+func (r *mysqlRepository) publish(ctx context.Context, tx *sql.Tx, action, ID string) error {
+	var payload interface{}
+	if action != outbox.ActionDelete {
+		u, err := r.findOne(ctx, tx, `SELECT `+userColumns+` FROM user WHERE id = ?;`, ID)
+		if err != nil {
+			return err
+		}
+		payload = u
+	}
+
+	m, err := outbox.NewMessage(ctx, outbox.AggregateUser, ID, action, payload)
 	if err != nil {
 		r.logger.Error(err)
 		return err
 	}
+	if err = r.outbox.Add(ctx, tx, m); err != nil {
+		return err
+	}
+
+	if action == outbox.ActionDelete || action == outbox.ActionRestore {
+		return mysql_profile.PublishChange(ctx, tx, r.outbox, r.logger, action, ID)
+	}
+	return nil
+}
 
+// requireAffected reports sql.ErrNoRows when a statement matched nothing.
+func requireAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
 	return nil
 }
 
-func NewMySQLRepository(client mysql.Client, logger *logging.Logger) user.Repository {
+func NewMySQLRepository(client mysql.Client, outbox outbox.Repository, logger *logging.Logger) user.Repository {
 	return &mysqlRepository{
 		client: client,
+		outbox: outbox,
 		logger: logger,
 	}
 }
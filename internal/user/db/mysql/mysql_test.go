@@ -0,0 +1,75 @@
+package mysql_user
+
+import (
+	"awesome-clean-arch/internal/outbox/db/mysql"
+	"awesome-clean-arch/internal/profile"
+	"awesome-clean-arch/internal/profile/db/mysql"
+	"awesome-clean-arch/internal/user"
+	"awesome-clean-arch/pkg/logging"
+	"context"
+	"database/sql"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// testDB connects to the database of APP_TEST_MYSQL_DSN, loaded with
+// data/scheme.sql, or skips the test when it is unset.
+func testDB(t *testing.T) *sql.DB {
+	dsn := os.Getenv("APP_TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("APP_TEST_MYSQL_DSN is not set")
+	}
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestRestoreKeepsProfileDeletedOnItsOwn(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	logger := logging.GetLogger()
+	ob := mysql_outbox.NewMySQLRepository(db, logger)
+	users := NewMySQLRepository(db, ob, logger)
+	profiles := mysql_profile.NewMySQLRepository(db, ob, logger)
+
+	username := "restore-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	id, err := profiles.Create(ctx, profile.Profile{Username: username, FirstName: "Ada", School: "42"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := profiles.FindOne(ctx, username, profile.FindOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = profiles.Delete(ctx, id, p.Version); err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := users.FindOne(ctx, id, user.FindOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = users.Delete(ctx, id, u.Version); err != nil {
+		t.Fatal(err)
+	}
+	if err = users.Restore(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = users.FindOne(ctx, id, user.FindOptions{}); err != nil {
+		t.Errorf("the user was not restored: %s", err)
+	}
+	found, err := profiles.FindByUserIDs(ctx, []string{id}, profile.FindOptions{IncludeDeleted: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].DeletedAt == nil {
+		t.Errorf("the profile deleted before the user was restored with it: %+v", found)
+	}
+}
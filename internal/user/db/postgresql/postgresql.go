@@ -7,7 +7,10 @@ import (
 	"context"
 	"fmt"
 	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type pgRepository struct {
@@ -33,15 +36,44 @@ func (r *pgRepository) Create(ctx context.Context, user user.User) (string, erro
 		}
 		return "", err
 	}
-	return string(user.ID), nil
+	return strconv.Itoa(user.ID), nil
 }
 
-func (r *pgRepository) FindAll(ctx context.Context) (u []user.User, err error) {
-	q := `SELECT id, username FROM awesome.user;`
+func (r *pgRepository) FindAll(ctx context.Context, opts user.FindOptions) (u []user.User, err error) {
+	q := `SELECT id, username, version, deleted_at FROM awesome.user`
+	if !opts.IncludeDeleted {
+		q += ` WHERE deleted_at IS NULL`
+	}
+	q += `;`
+
+	return r.findMany(ctx, q)
+}
+
+// FindByIDs returns the users among IDs, in no particular order.
+func (r *pgRepository) FindByIDs(ctx context.Context, IDs []string, opts user.FindOptions) ([]user.User, error) {
+	ids := make([]int, 0, len(IDs))
+	for _, ID := range IDs {
+		if id, err := strconv.Atoi(ID); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	q := `SELECT id, username, version, deleted_at FROM awesome.user WHERE id = ANY($1)`
+	if !opts.IncludeDeleted {
+		q += ` AND deleted_at IS NULL`
+	}
+	q += `;`
 
+	return r.findMany(ctx, q, ids)
+}
+
+func (r *pgRepository) findMany(ctx context.Context, q string, args ...interface{}) ([]user.User, error) {
 	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
 
-	rows, err := r.client.Query(ctx, q)
+	rows, err := r.client.Query(ctx, q, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -51,7 +83,7 @@ func (r *pgRepository) FindAll(ctx context.Context) (u []user.User, err error) {
 	for rows.Next() {
 		var u user.User
 
-		err = rows.Scan(&u.ID, &u.Username)
+		err := rows.Scan(&u.ID, &u.Username, &u.Version, &u.DeletedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -66,14 +98,18 @@ func (r *pgRepository) FindAll(ctx context.Context) (u []user.User, err error) {
 	return users, nil
 }
 
-func (r *pgRepository) FindOne(ctx context.Context, ID string) (user.User, error) {
-	q := `SELECT id, username FROM awesome.user WHERE id = $1;`
+func (r *pgRepository) FindOne(ctx context.Context, ID string, opts user.FindOptions) (user.User, error) {
+	q := `SELECT id, username, version, deleted_at FROM awesome.user WHERE id = $1`
+	if !opts.IncludeDeleted {
+		q += ` AND deleted_at IS NULL`
+	}
+	q += `;`
 
 	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
 
 	var u user.User
 
-	err := r.client.QueryRow(ctx, q, ID).Scan(u.ID, u.Username)
+	err := r.client.QueryRow(ctx, q, ID).Scan(&u.ID, &u.Username, &u.Version, &u.DeletedAt)
 	if err != nil {
 		return user.User{}, err
 	}
@@ -82,31 +118,76 @@ func (r *pgRepository) FindOne(ctx context.Context, ID string) (user.User, error
 }
 
 func (r *pgRepository) Update(ctx context.Context, user user.User) error {
-	q := `UPDATE awesome.user SET username = $1 WHERE id = $2;`
+	q := `UPDATE awesome.user SET username = $1, version = version + 1 WHERE id = $2 AND version = $3 AND deleted_at IS NULL;`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	tag, err := r.client.Exec(ctx, q, user.Username, user.ID, user.Version)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() > 0 {
+		return nil
+	}
+	return r.notUpdated(ctx, strconv.Itoa(user.ID))
+}
+
+// notUpdated tells a missing user apart from a version mismatch.
+func (r *pgRepository) notUpdated(ctx context.Context, ID string) error {
+	if _, err := r.FindOne(ctx, ID, user.FindOptions{}); err != nil {
+		return err
+	}
+	return user.ErrConflict
+}
+
+func (r *pgRepository) Delete(ctx context.Context, ID string, version int) error {
+	q := `UPDATE awesome.user SET deleted_at = now() WHERE id = $1 AND version = $2 AND deleted_at IS NULL;`
 
 	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
 
-	_, err := r.client.Exec(ctx, q, user.Username, user.ID)
+	tag, err := r.client.Exec(ctx, q, ID, version)
 	if err != nil {
 		return err
 	}
+	if tag.RowsAffected() == 0 {
+		return r.notUpdated(ctx, ID)
+	}
 
 	return nil
 }
 
-func (r *pgRepository) Delete(ctx context.Context, ID string) error {
-	q := `DELETE FROM awesome.user WHERE id = $1;`
+// Restore brings back a soft-deleted user. Its profile comes back with it,
+// unless the profile was deleted on its own.
+func (r *pgRepository) Restore(ctx context.Context, ID string) error {
+	q := `UPDATE awesome.user SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL;`
 
 	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
 
-	_, err := r.client.Exec(ctx, q, ID)
+	tag, err := r.client.Exec(ctx, q, ID)
 	if err != nil {
 		return err
 	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
 
 	return nil
 }
 
+func (r *pgRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	q := `DELETE FROM awesome.user WHERE deleted_at IS NOT NULL AND deleted_at < $1;`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	tag, err := r.client.Exec(ctx, q, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
 func NewPGRepository(client postgresql.Client, logger *logging.Logger) user.Repository {
 	return &pgRepository{
 		client: client,
@@ -0,0 +1,159 @@
+package user
+
+import (
+	"awesome-clean-arch/internal/auth"
+	"awesome-clean-arch/internal/grpcapi"
+	apiv1 "awesome-clean-arch/pkg/api/v1"
+	"awesome-clean-arch/pkg/logging"
+	"awesome-clean-arch/pkg/validate"
+	"context"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"strconv"
+)
+
+var _ grpcapi.Service = &grpcService{}
+
+type grpcService struct {
+	apiv1.UnimplementedUserServiceServer
+	logger     *logging.Logger
+	repository Repository
+}
+
+func NewGRPCService(logger *logging.Logger, repository Repository) grpcapi.Service {
+	return &grpcService{
+		logger:     logger,
+		repository: repository,
+	}
+}
+
+func (s *grpcService) Register(server *grpc.Server) {
+	apiv1.RegisterUserServiceServer(server, s)
+}
+
+func (s *grpcService) Scopes() map[string]string {
+	desc := apiv1.UserService_ServiceDesc
+	return map[string]string{
+		grpcapi.FullMethod(desc, "CreateUser"):  auth.ScopeUsersWrite,
+		grpcapi.FullMethod(desc, "ListUsers"):   auth.ScopeUsersRead,
+		grpcapi.FullMethod(desc, "GetUser"):     auth.ScopeUsersRead,
+		grpcapi.FullMethod(desc, "UpdateUser"):  auth.ScopeUsersWrite,
+		grpcapi.FullMethod(desc, "DeleteUser"):  auth.ScopeUsersWrite,
+		grpcapi.FullMethod(desc, "RestoreUser"): auth.ScopeAuthAdmin,
+	}
+}
+
+func (s *grpcService) CreateUser(ctx context.Context, req *apiv1.CreateUserRequest) (*apiv1.CreateUserResponse, error) {
+	user := userFromProto(req.GetUser())
+	if err := validate.Struct(user); err != nil {
+		return nil, grpcapi.ValidationError(err)
+	}
+
+	id, err := s.repository.Create(ctx, user)
+	if err != nil {
+		return nil, grpcapi.Error(err, "")
+	}
+	return &apiv1.CreateUserResponse{Id: id}, nil
+}
+
+func (s *grpcService) ListUsers(ctx context.Context, req *apiv1.ListUsersRequest) (*apiv1.ListUsersResponse, error) {
+	if req.GetIncludeDeleted() && !auth.IsAdmin(ctx) {
+		return nil, status.Error(codes.PermissionDenied, "include_deleted requires scope "+auth.ScopeAuthAdmin)
+	}
+
+	all, err := s.repository.FindAll(ctx, FindOptions{IncludeDeleted: req.GetIncludeDeleted()})
+	if err != nil {
+		return nil, grpcapi.Error(err, "")
+	}
+
+	users := make([]*apiv1.User, len(all))
+	for i, u := range all {
+		users[i] = userToProto(u)
+	}
+	return &apiv1.ListUsersResponse{Users: users}, nil
+}
+
+func (s *grpcService) GetUser(ctx context.Context, req *apiv1.GetUserRequest) (*apiv1.User, error) {
+	if req.GetIncludeDeleted() && !auth.IsAdmin(ctx) {
+		return nil, status.Error(codes.PermissionDenied, "include_deleted requires scope "+auth.ScopeAuthAdmin)
+	}
+
+	user, err := s.repository.FindOne(ctx, req.GetId(), FindOptions{IncludeDeleted: req.GetIncludeDeleted()})
+	if err != nil {
+		return nil, grpcapi.Error(err, "User not found")
+	}
+	return userToProto(user), nil
+}
+
+func (s *grpcService) UpdateUser(ctx context.Context, req *apiv1.UpdateUserRequest) (*apiv1.User, error) {
+	user := userFromProto(req.GetUser())
+	if err := validate.Struct(user); err != nil {
+		return nil, grpcapi.ValidationError(err)
+	}
+	if user.Version == 0 {
+		return nil, grpcapi.VersionRequired()
+	}
+
+	if err := s.repository.Update(ctx, user); err != nil {
+		if errors.Is(err, ErrConflict) {
+			return nil, grpcapi.Conflict(err)
+		}
+		return nil, grpcapi.Error(err, "User not found")
+	}
+
+	user, err := s.repository.FindOne(ctx, strconv.Itoa(user.ID), FindOptions{})
+	if err != nil {
+		return nil, grpcapi.Error(err, "User not found")
+	}
+	return userToProto(user), nil
+}
+
+func (s *grpcService) DeleteUser(ctx context.Context, req *apiv1.DeleteUserRequest) (*emptypb.Empty, error) {
+	user, err := s.repository.FindOne(ctx, req.GetId(), FindOptions{})
+	if err != nil {
+		return nil, grpcapi.Error(err, "User not found")
+	}
+	if req.GetVersion() != 0 && int(req.GetVersion()) != user.Version {
+		return nil, grpcapi.Conflict(ErrConflict)
+	}
+
+	if err = s.repository.Delete(ctx, req.GetId(), user.Version); err != nil {
+		if errors.Is(err, ErrConflict) {
+			return nil, grpcapi.Conflict(err)
+		}
+		return nil, grpcapi.Error(err, "User not found")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *grpcService) RestoreUser(ctx context.Context, req *apiv1.RestoreUserRequest) (*apiv1.User, error) {
+	if err := s.repository.Restore(ctx, req.GetId()); err != nil {
+		return nil, grpcapi.Error(err, "Deleted user not found")
+	}
+
+	user, err := s.repository.FindOne(ctx, req.GetId(), FindOptions{})
+	if err != nil {
+		return nil, grpcapi.Error(err, "User not found")
+	}
+	return userToProto(user), nil
+}
+
+func userToProto(u User) *apiv1.User {
+	return &apiv1.User{
+		Id:        int64(u.ID),
+		Username:  u.Username,
+		Version:   int32(u.Version),
+		DeletedAt: grpcapi.Timestamp(u.DeletedAt),
+	}
+}
+
+func userFromProto(u *apiv1.User) User {
+	return User{
+		ID:       int(u.GetId()),
+		Username: u.GetUsername(),
+		Version:  int(u.GetVersion()),
+	}
+}
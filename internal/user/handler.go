@@ -1,18 +1,21 @@
 package user
 
 import (
+	"awesome-clean-arch/internal/auth"
 	"awesome-clean-arch/internal/handlers"
 	"awesome-clean-arch/pkg/logging"
-	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"github.com/julienschmidt/httprouter"
 	"net/http"
+	"strconv"
 )
 
 const (
-	usersURL = "/user"
-	userURL  = "/user/:id"
+	usersURL       = "/user"
+	userURL        = "/user/:id"
+	restoreUserURL = "/user/:id/restore"
 )
 
 var _ handlers.Handler = &handler{}
@@ -33,14 +36,44 @@ func NewHandler(logger *logging.Logger, repository Repository) handlers.Handler
 	}
 }
 
-func (h *handler) Register(router *httprouter.Router) {
-	router.GET(usersURL, h.GetUsersList)
-	router.GET(userURL, h.GetUser)
+func (h *handler) Middleware() []handlers.Middleware {
+	return nil
+}
+
+func (h *handler) Register(router handlers.Router) {
+	router.GET(usersURL, h.GetUsersList, auth.RequireScope(auth.ScopeUsersRead))
+	router.GET(userURL, h.GetUser, auth.RequireScope(auth.ScopeUsersRead))
+	router.PUT(userURL, h.UpdateUser, auth.RequireScope(auth.ScopeUsersWrite))
+	router.DELETE(userURL, h.DeleteUser, auth.RequireScope(auth.ScopeUsersWrite))
+	router.POST(restoreUserURL, h.RestoreUser, auth.RequireScope(auth.ScopeAuthAdmin))
+}
+
+func (h *handler) Docs() []handlers.Doc {
+	tags := []string{"users"}
+	return []handlers.Doc{
+		{Method: http.MethodGet, Path: usersURL, Summary: "List users", Tags: tags, Scope: auth.ScopeUsersRead,
+			Query: []handlers.Param{{Name: "include_deleted", Description: "Also return soft-deleted rows, admin only"}}, Response: []User{}},
+		{Method: http.MethodGet, Path: userURL, Summary: "Get a user", Tags: tags, Scope: auth.ScopeUsersRead,
+			Query: []handlers.Param{{Name: "include_deleted", Description: "Also return soft-deleted rows, admin only"}}, Response: User{}, Conditional: true},
+		{Method: http.MethodPut, Path: userURL, Summary: "Rename a user", Tags: tags, Scope: auth.ScopeUsersWrite,
+			Request: User{}, Response: User{}, Conditional: true},
+		{Method: http.MethodDelete, Path: userURL, Summary: "Soft-delete a user", Tags: tags, Scope: auth.ScopeUsersWrite,
+			Status: http.StatusNoContent, Conditional: true},
+		{Method: http.MethodPost, Path: restoreUserURL, Summary: "Restore a soft-deleted user and its profile", Tags: tags,
+			Scope: auth.ScopeAuthAdmin, Response: User{}},
+	}
 }
 
 func (h *handler) GetUsersList(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	w.Header().Set("Content-Type", "application/json")
-	userList, err := h.repository.FindAll(context.TODO())
+
+	includeDeleted, ok := auth.IncludeDeleted(r)
+	if !ok {
+		handlers.WriteError(w, http.StatusForbidden, "include_deleted requires scope "+auth.ScopeAuthAdmin)
+		return
+	}
+
+	userList, err := h.repository.FindAll(r.Context(), FindOptions{IncludeDeleted: includeDeleted})
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		errorResponse := ErrorResponse{Error: err.Error()}
@@ -65,7 +98,13 @@ func (h *handler) GetUser(w http.ResponseWriter, r *http.Request, params httprou
 
 	userID := params.ByName("id")
 
-	user, err := h.repository.FindOne(context.TODO(), userID)
+	includeDeleted, ok := auth.IncludeDeleted(r)
+	if !ok {
+		handlers.WriteError(w, http.StatusForbidden, "include_deleted requires scope "+auth.ScopeAuthAdmin)
+		return
+	}
+
+	user, err := h.repository.FindOne(r.Context(), userID, FindOptions{IncludeDeleted: includeDeleted})
 	if err != nil {
 		if err == sql.ErrNoRows {
 			w.WriteHeader(http.StatusNotFound)
@@ -80,6 +119,11 @@ func (h *handler) GetUser(w http.ResponseWriter, r *http.Request, params httprou
 		return
 	}
 
+	etag := handlers.ETag(user.Version)
+	if handlers.NotModified(w, r, etag) {
+		return
+	}
+
 	userJSON, err := json.Marshal(user)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -88,6 +132,105 @@ func (h *handler) GetUser(w http.ResponseWriter, r *http.Request, params httprou
 		return
 	}
 
+	w.Header().Set("ETag", etag)
 	w.WriteHeader(http.StatusOK)
 	w.Write(userJSON)
 }
+
+func (h *handler) UpdateUser(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var user User
+	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
+		handlers.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !handlers.Validate(w, r, user) {
+		return
+	}
+
+	userID := params.ByName("id")
+	id, err := strconv.Atoi(userID)
+	if err != nil {
+		handlers.WriteError(w, http.StatusBadRequest, "invalid user id")
+		return
+	}
+	user.ID = id
+
+	var ok bool
+	if user.Version, ok = handlers.ExpectedVersion(w, r, user.Version); !ok {
+		return
+	}
+
+	if err = h.repository.Update(r.Context(), user); err != nil {
+		switch {
+		case err == sql.ErrNoRows:
+			handlers.WriteError(w, http.StatusNotFound, "User not found")
+		case errors.Is(err, ErrConflict):
+			handlers.WriteConflict(w, r, err.Error())
+		default:
+			handlers.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	user, err = h.repository.FindOne(r.Context(), userID, FindOptions{})
+	if err != nil {
+		handlers.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("ETag", handlers.ETag(user.Version))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(user)
+}
+
+func (h *handler) DeleteUser(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	userID := params.ByName("id")
+
+	user, err := h.repository.FindOne(r.Context(), userID, FindOptions{})
+	if err == nil {
+		if !handlers.CheckIfMatch(w, r, user.Version) {
+			return
+		}
+		err = h.repository.Delete(r.Context(), userID, user.Version)
+	}
+	if err != nil {
+		switch {
+		case err == sql.ErrNoRows:
+			handlers.WriteError(w, http.StatusNotFound, "User not found")
+		case errors.Is(err, ErrConflict):
+			handlers.WriteConflict(w, r, err.Error())
+		default:
+			handlers.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler) RestoreUser(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID := params.ByName("id")
+
+	err := h.repository.Restore(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			handlers.WriteError(w, http.StatusNotFound, "Deleted user not found")
+			return
+		}
+		handlers.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	user, err := h.repository.FindOne(r.Context(), userID, FindOptions{})
+	if err != nil {
+		handlers.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(user)
+}
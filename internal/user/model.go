@@ -1,6 +1,17 @@
 package user
 
+import (
+	"errors"
+	"time"
+)
+
+// ErrConflict is returned by Update and Delete when the stored version
+// differs from the one the caller read.
+var ErrConflict = errors.New("user was modified concurrently")
+
 type User struct {
-	ID       int    `json:"id"`
-	Username string `json:"username"`
+	ID        int        `json:"id"`
+	Username  string     `json:"username" validate:"required,max=64"`
+	Version   int        `json:"version"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
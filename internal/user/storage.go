@@ -1,11 +1,28 @@
 package user
 
-import "context"
+import (
+	"context"
+	"time"
+)
+
+// FindOptions tunes lookups. Soft-deleted users are skipped unless
+// IncludeDeleted is set.
+type FindOptions struct {
+	IncludeDeleted bool
+}
 
 type Repository interface {
 	Create(ctx context.Context, user User) (string, error)
-	FindAll(ctx context.Context) (u []User, err error)
-	FindOne(ctx context.Context, ID string) (User, error)
+	FindAll(ctx context.Context, opts FindOptions) (u []User, err error)
+	FindOne(ctx context.Context, ID string, opts FindOptions) (User, error)
+	FindByIDs(ctx context.Context, IDs []string, opts FindOptions) ([]User, error)
+	// Update succeeds only while the stored version equals the given one and
+	// bumps it, otherwise it returns ErrConflict.
 	Update(ctx context.Context, user User) error
-	Delete(ctx context.Context, ID string) error
+	// Delete marks the user deleted while its stored version equals the given
+	// one, otherwise it returns ErrConflict. The user is removed for good by
+	// Purge once deleted before the given time. Restore undoes Delete.
+	Delete(ctx context.Context, ID string, version int) error
+	Restore(ctx context.Context, ID string) error
+	Purge(ctx context.Context, before time.Time) (int64, error)
 }
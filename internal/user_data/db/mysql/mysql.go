@@ -1,17 +1,22 @@
 package mysql_user_data
 
 import (
+	"awesome-clean-arch/internal/outbox"
 	"awesome-clean-arch/internal/user_data"
 	"awesome-clean-arch/pkg/client/mysql"
 	"awesome-clean-arch/pkg/logging"
 	"context"
+	"database/sql"
 	"fmt"
 	"strconv"
 	"strings"
 )
 
+const userDataColumns = `user_data.user_id, user_data.school, user_data.version`
+
 type mysqlRepository struct {
 	client mysql.Client
+	outbox outbox.Repository
 	logger *logging.Logger
 }
 
@@ -24,7 +29,14 @@ func (r *mysqlRepository) Create(ctx context.Context, ud user_data.UserData) (st
 
 	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
 
-	res, err := r.client.ExecContext(ctx, q, ud.School)
+	tx, err := r.client.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error(err)
+		return "", err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, q, ud.School)
 	if err != nil {
 		r.logger.Error(err)
 		return "", err
@@ -36,15 +48,44 @@ func (r *mysqlRepository) Create(ctx context.Context, ud user_data.UserData) (st
 		return "", err
 	}
 
-	return strconv.FormatInt(id, 10), nil
+	ID := strconv.FormatInt(id, 10)
+	if err = r.publish(ctx, tx, outbox.ActionCreate, ID); err != nil {
+		return "", err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.logger.Error(err)
+		return "", err
+	}
+
+	return ID, nil
 }
 
 func (r *mysqlRepository) FindAll(ctx context.Context) (ud []user_data.UserData, err error) {
-	q := `SELECT user_id, school FROM user_data;`
+	q := `SELECT ` + userDataColumns + ` FROM user_data
+	JOIN user ON user.id = user_data.user_id WHERE user.deleted_at IS NULL;`
+
+	return r.findMany(ctx, q)
+}
+
+// FindByUserIDs returns the user data of the users among userIDs, in no
+// particular order.
+func (r *mysqlRepository) FindByUserIDs(ctx context.Context, userIDs []string) ([]user_data.UserData, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+
+	in, args := mysql.In(userIDs)
+	q := `SELECT ` + userDataColumns + ` FROM user_data
+	JOIN user ON user.id = user_data.user_id WHERE user_data.user_id IN (` + in + `) AND user.deleted_at IS NULL;`
 
+	return r.findMany(ctx, q, args...)
+}
+
+func (r *mysqlRepository) findMany(ctx context.Context, q string, args ...interface{}) ([]user_data.UserData, error) {
 	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
 
-	rows, err := r.client.QueryContext(ctx, q)
+	rows, err := r.client.QueryContext(ctx, q, args...)
 	if err != nil {
 		r.logger.Error(err)
 		return nil, err
@@ -56,7 +97,7 @@ func (r *mysqlRepository) FindAll(ctx context.Context) (ud []user_data.UserData,
 	for rows.Next() {
 		var d user_data.UserData
 
-		err = rows.Scan(&d.ID, &d.School)
+		err := rows.Scan(&d.ID, &d.School, &d.Version)
 		if err != nil {
 			r.logger.Error(err)
 			return nil, err
@@ -74,52 +115,138 @@ func (r *mysqlRepository) FindAll(ctx context.Context) (ud []user_data.UserData,
 }
 
 func (r *mysqlRepository) FindOne(ctx context.Context, ID string) (user_data.UserData, error) {
-	q := `SELECT user_id, school FROM user_data WHERE user_id = ?;`
+	q := `SELECT ` + userDataColumns + ` FROM user_data
+	JOIN user ON user.id = user_data.user_id WHERE user_data.user_id = ? AND user.deleted_at IS NULL;`
 
 	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
 
 	var ud user_data.UserData
 
-	err := r.client.QueryRowContext(ctx, q, ID).Scan(&ud.ID, &ud.School)
+	err := r.client.QueryRowContext(ctx, q, ID).Scan(&ud.ID, &ud.School, &ud.Version)
 	if err != nil {
-		r.logger.Error(err)
+		if err != sql.ErrNoRows {
+			r.logger.Error(err)
+		}
 		return user_data.UserData{}, err
 	}
 
 	return ud, nil
 }
 
+// The writes below skip the data of soft-deleted users, which reads hide.
+
 func (r *mysqlRepository) Update(ctx context.Context, ud user_data.UserData) error {
-	q := `UPDATE user_data SET school = ? WHERE user_id = ?;`
+	q := `UPDATE user_data JOIN user ON user.id = user_data.user_id
+	SET user_data.school = ?, user_data.version = user_data.version + 1
+	WHERE user_data.user_id = ? AND user_data.version = ? AND user.deleted_at IS NULL;`
+
+	err := r.change(ctx, outbox.ActionUpdate, strconv.Itoa(ud.ID), q, ud.School, ud.ID, ud.Version)
+	if err != sql.ErrNoRows {
+		return err
+	}
+	// Tell a missing row apart from a version mismatch.
+	if _, err = r.FindOne(ctx, strconv.Itoa(ud.ID)); err != nil {
+		return err
+	}
+	return user_data.ErrConflict
+}
+
+func (r *mysqlRepository) Delete(ctx context.Context, ID string) error {
+	q := `DELETE user_data FROM user_data JOIN user ON user.id = user_data.user_id
+	WHERE user_data.user_id = ? AND user.deleted_at IS NULL;`
+
+	err := r.change(ctx, outbox.ActionDelete, ID, q, ID)
+	if err != sql.ErrNoRows {
+		return err
+	}
+	// Deleting missing user data is not an error, unless the user is missing.
+	return r.userExists(ctx, ID)
+}
+
+// userExists reports sql.ErrNoRows when the user ID is missing or deleted.
+func (r *mysqlRepository) userExists(ctx context.Context, ID string) error {
+	q := `SELECT id FROM user WHERE id = ? AND deleted_at IS NULL;`
 
 	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
 
-	_, err := r.client.ExecContext(ctx, q, ud.School, ud.ID)
-	if err != nil {
-		r.logger.Error(err)
+	var id int
+	if err := r.client.QueryRowContext(ctx, q, ID).Scan(&id); err != nil {
+		if err != sql.ErrNoRows {
+			r.logger.Error(err)
+		}
 		return err
 	}
 
 	return nil
 }
 
-func (r *mysqlRepository) Delete(ctx context.Context, ID string) error {
-	q := `DELETE FROM user_data WHERE user_id = ?;`
-
+// change runs the statement q of an action on the user data of ID together
+// with its outbox message. It reports sql.ErrNoRows when q matched nothing.
+func (r *mysqlRepository) change(ctx context.Context, action, ID, q string, args ...interface{}) error {
 	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
 
-	_, err := r.client.ExecContext(ctx, q, ID)
+	tx, err := r.client.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error(err)
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, q, args...)
 	if err != nil {
 		r.logger.Error(err)
 		return err
 	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		r.logger.Error(err)
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+
+	if err = r.publish(ctx, tx, action, ID); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.logger.Error(err)
+		return err
+	}
 
 	return nil
 }
 
-func NewMySQLRepository(client mysql.Client, logger *logging.Logger) user_data.Repository {
+// publish adds the message of a change to the outbox in tx. The payload is
+// the user data as tx sees it, none for deletions.
+func (r *mysqlRepository) publish(ctx context.Context, tx *sql.Tx, action, ID string) error {
+	var payload interface{}
+	if action != outbox.ActionDelete {
+		q := `SELECT ` + userDataColumns + ` FROM user_data WHERE user_id = ?;`
+
+		r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+		var ud user_data.UserData
+		if err := tx.QueryRowContext(ctx, q, ID).Scan(&ud.ID, &ud.School, &ud.Version); err != nil {
+			r.logger.Error(err)
+			return err
+		}
+		payload = ud
+	}
+
+	m, err := outbox.NewMessage(ctx, outbox.AggregateUserData, ID, action, payload)
+	if err != nil {
+		r.logger.Error(err)
+		return err
+	}
+	return r.outbox.Add(ctx, tx, m)
+}
+
+func NewMySQLRepository(client mysql.Client, outbox outbox.Repository, logger *logging.Logger) user_data.Repository {
 	return &mysqlRepository{
 		client: client,
+		outbox: outbox,
 		logger: logger,
 	}
 }
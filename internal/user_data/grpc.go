@@ -0,0 +1,124 @@
+package user_data
+
+import (
+	"awesome-clean-arch/internal/auth"
+	"awesome-clean-arch/internal/grpcapi"
+	apiv1 "awesome-clean-arch/pkg/api/v1"
+	"awesome-clean-arch/pkg/logging"
+	"awesome-clean-arch/pkg/validate"
+	"context"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"strconv"
+)
+
+var _ grpcapi.Service = &grpcService{}
+
+type grpcService struct {
+	apiv1.UnimplementedUserDataServiceServer
+	logger     *logging.Logger
+	repository Repository
+}
+
+func NewGRPCService(logger *logging.Logger, repository Repository) grpcapi.Service {
+	return &grpcService{
+		logger:     logger,
+		repository: repository,
+	}
+}
+
+func (s *grpcService) Register(server *grpc.Server) {
+	apiv1.RegisterUserDataServiceServer(server, s)
+}
+
+func (s *grpcService) Scopes() map[string]string {
+	desc := apiv1.UserDataService_ServiceDesc
+	return map[string]string{
+		grpcapi.FullMethod(desc, "CreateUserData"): auth.ScopeUserDataWrite,
+		grpcapi.FullMethod(desc, "ListUserData"):   auth.ScopeUserDataRead,
+		grpcapi.FullMethod(desc, "GetUserData"):    auth.ScopeUserDataRead,
+		grpcapi.FullMethod(desc, "UpdateUserData"): auth.ScopeUserDataWrite,
+		grpcapi.FullMethod(desc, "DeleteUserData"): auth.ScopeUserDataWrite,
+	}
+}
+
+func (s *grpcService) CreateUserData(ctx context.Context, req *apiv1.CreateUserDataRequest) (*apiv1.CreateUserDataResponse, error) {
+	userData := userDataFromProto(req.GetUserData())
+	if err := validate.Struct(userData); err != nil {
+		return nil, grpcapi.ValidationError(err)
+	}
+
+	id, err := s.repository.Create(ctx, userData)
+	if err != nil {
+		return nil, grpcapi.Error(err, "")
+	}
+	return &apiv1.CreateUserDataResponse{Id: id}, nil
+}
+
+func (s *grpcService) ListUserData(ctx context.Context, req *apiv1.ListUserDataRequest) (*apiv1.ListUserDataResponse, error) {
+	all, err := s.repository.FindAll(ctx)
+	if err != nil {
+		return nil, grpcapi.Error(err, "")
+	}
+
+	userData := make([]*apiv1.UserData, len(all))
+	for i, ud := range all {
+		userData[i] = userDataToProto(ud)
+	}
+	return &apiv1.ListUserDataResponse{UserData: userData}, nil
+}
+
+func (s *grpcService) GetUserData(ctx context.Context, req *apiv1.GetUserDataRequest) (*apiv1.UserData, error) {
+	userData, err := s.repository.FindOne(ctx, req.GetUserId())
+	if err != nil {
+		return nil, grpcapi.Error(err, "User data not found")
+	}
+	return userDataToProto(userData), nil
+}
+
+func (s *grpcService) UpdateUserData(ctx context.Context, req *apiv1.UpdateUserDataRequest) (*apiv1.UserData, error) {
+	userData := userDataFromProto(req.GetUserData())
+	if err := validate.Struct(userData); err != nil {
+		return nil, grpcapi.ValidationError(err)
+	}
+	if userData.Version == 0 {
+		return nil, grpcapi.VersionRequired()
+	}
+
+	if err := s.repository.Update(ctx, userData); err != nil {
+		if errors.Is(err, ErrConflict) {
+			return nil, grpcapi.Conflict(err)
+		}
+		return nil, grpcapi.Error(err, "User data not found")
+	}
+
+	userData, err := s.repository.FindOne(ctx, strconv.Itoa(userData.ID))
+	if err != nil {
+		return nil, grpcapi.Error(err, "User data not found")
+	}
+	return userDataToProto(userData), nil
+}
+
+func (s *grpcService) DeleteUserData(ctx context.Context, req *apiv1.DeleteUserDataRequest) (*emptypb.Empty, error) {
+	if err := s.repository.Delete(ctx, req.GetUserId()); err != nil {
+		return nil, grpcapi.Error(err, "User data not found")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func userDataToProto(ud UserData) *apiv1.UserData {
+	return &apiv1.UserData{
+		UserId:  int64(ud.ID),
+		School:  ud.School,
+		Version: int32(ud.Version),
+	}
+}
+
+func userDataFromProto(ud *apiv1.UserData) UserData {
+	return UserData{
+		ID:      int(ud.GetUserId()),
+		School:  ud.GetSchool(),
+		Version: int(ud.GetVersion()),
+	}
+}
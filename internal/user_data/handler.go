@@ -1,12 +1,15 @@
 package user_data
 
 import (
+	"awesome-clean-arch/internal/auth"
 	"awesome-clean-arch/internal/handlers"
 	"awesome-clean-arch/pkg/logging"
-	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"github.com/julienschmidt/httprouter"
 	"net/http"
+	"strconv"
 )
 
 const (
@@ -28,14 +31,31 @@ func NewHandler(logger *logging.Logger, repository Repository) handlers.Handler
 	}
 }
 
-func (h *handler) Register(router *httprouter.Router) {
-	router.GET(userDatasURL, h.GetUserDataList)
-	router.GET(userDataURL, h.GetUserData)
+func (h *handler) Middleware() []handlers.Middleware {
+	return nil
+}
+
+func (h *handler) Register(router handlers.Router) {
+	router.GET(userDatasURL, h.GetUserDataList, auth.RequireScope(auth.ScopeUserDataRead))
+	router.GET(userDataURL, h.GetUserData, auth.RequireScope(auth.ScopeUserDataRead))
+	router.PUT(userDataURL, h.UpdateUserData, auth.RequireScope(auth.ScopeUserDataWrite))
+}
+
+func (h *handler) Docs() []handlers.Doc {
+	tags := []string{"user data"}
+	return []handlers.Doc{
+		{Method: http.MethodGet, Path: userDatasURL, Summary: "List user data", Tags: tags, Scope: auth.ScopeUserDataRead,
+			Response: []UserData{}},
+		{Method: http.MethodGet, Path: userDataURL, Summary: "Get the data of a user", Tags: tags, Scope: auth.ScopeUserDataRead,
+			Response: UserData{}, Conditional: true},
+		{Method: http.MethodPut, Path: userDataURL, Summary: "Replace the data of a user", Tags: tags, Scope: auth.ScopeUserDataWrite,
+			Request: UserData{}, Response: UserData{}, Conditional: true},
+	}
 }
 
 func (h *handler) GetUserDataList(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	w.Header().Set("Content-Type", "application/json")
-	all, err := h.repository.FindAll(context.TODO())
+	all, err := h.repository.FindAll(r.Context())
 	if err != nil {
 		w.WriteHeader(400)
 		return
@@ -53,5 +73,72 @@ func (h *handler) GetUserDataList(w http.ResponseWriter, r *http.Request, params
 }
 
 func (h *handler) GetUserData(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
-	w.Write([]byte("user id"))
+	w.Header().Set("Content-Type", "application/json")
+
+	userData, err := h.repository.FindOne(r.Context(), params.ByName("user_id"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			handlers.WriteError(w, http.StatusNotFound, "User data not found")
+			return
+		}
+		handlers.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	etag := handlers.ETag(userData.Version)
+	if handlers.NotModified(w, r, etag) {
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(userData)
+}
+
+func (h *handler) UpdateUserData(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var userData UserData
+	if err := json.NewDecoder(r.Body).Decode(&userData); err != nil {
+		handlers.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !handlers.Validate(w, r, userData) {
+		return
+	}
+
+	userID := params.ByName("user_id")
+	id, err := strconv.Atoi(userID)
+	if err != nil {
+		handlers.WriteError(w, http.StatusBadRequest, "invalid user id")
+		return
+	}
+	userData.ID = id
+
+	var ok bool
+	if userData.Version, ok = handlers.ExpectedVersion(w, r, userData.Version); !ok {
+		return
+	}
+
+	if err = h.repository.Update(r.Context(), userData); err != nil {
+		switch {
+		case err == sql.ErrNoRows:
+			handlers.WriteError(w, http.StatusNotFound, "User data not found")
+		case errors.Is(err, ErrConflict):
+			handlers.WriteConflict(w, r, err.Error())
+		default:
+			handlers.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	userData, err = h.repository.FindOne(r.Context(), userID)
+	if err != nil {
+		handlers.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("ETag", handlers.ETag(userData.Version))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(userData)
 }
@@ -1,6 +1,13 @@
 package user_data
 
+import "errors"
+
+// ErrConflict is returned by Update when the stored version differs from
+// the one the caller read.
+var ErrConflict = errors.New("user data was modified concurrently")
+
 type UserData struct {
-	ID     int    `json:"user_id"`
-	School string `json:"school"`
+	ID      int    `json:"user_id"`
+	School  string `json:"school" validate:"required,max=32"`
+	Version int    `json:"version"`
 }
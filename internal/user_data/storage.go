@@ -6,6 +6,9 @@ type Repository interface {
 	Create(ctx context.Context, userData UserData) (string, error)
 	FindAll(ctx context.Context) (ud []UserData, err error)
 	FindOne(ctx context.Context, userID string) (UserData, error)
+	FindByUserIDs(ctx context.Context, userIDs []string) ([]UserData, error)
+	// Update succeeds only while the stored version equals the given one and
+	// bumps it, otherwise it returns ErrConflict.
 	Update(ctx context.Context, userData UserData) error
 	Delete(ctx context.Context, userID string) error
 }
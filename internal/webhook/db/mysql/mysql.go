@@ -0,0 +1,335 @@
+package mysql_webhook
+
+import (
+	"awesome-clean-arch/internal/webhook"
+	"awesome-clean-arch/pkg/client/mysql"
+	"awesome-clean-arch/pkg/logging"
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const maxLimit = 1000
+
+const (
+	webhookColumns  = `id, url, secret, event_types, active, created_at`
+	deliveryColumns = `id, webhook_id, event_id, event_type, payload, status, attempts, next_attempt_at, last_status_code,
+	last_error, created_at, delivered_at`
+)
+
+type mysqlRepository struct {
+	client mysql.Client
+	logger *logging.Logger
+}
+
+func formatQuery(q string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(q, "\t", ""), "\n", " ")
+}
+
+func (r *mysqlRepository) Create(ctx context.Context, w webhook.Webhook) (string, error) {
+	q := `INSERT INTO webhook (url, secret, event_types, active) VALUES (?, ?, ?, ?);`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	res, err := r.client.ExecContext(ctx, q, w.URL, w.Secret, strings.Join(w.EventTypes, ","), w.Active)
+	if err != nil {
+		r.logger.Error(err)
+		return "", err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		r.logger.Error(err)
+		return "", err
+	}
+
+	return strconv.FormatInt(id, 10), nil
+}
+
+func (r *mysqlRepository) FindAll(ctx context.Context) ([]webhook.Webhook, error) {
+	q := `SELECT ` + webhookColumns + ` FROM webhook;`
+
+	return r.findMany(ctx, q)
+}
+
+func (r *mysqlRepository) FindActive(ctx context.Context, eventType string) ([]webhook.Webhook, error) {
+	q := `SELECT ` + webhookColumns + ` FROM webhook WHERE active = 1;`
+
+	all, err := r.findMany(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	subscribed := make([]webhook.Webhook, 0, len(all))
+	for _, w := range all {
+		if w.Subscribed(eventType) {
+			subscribed = append(subscribed, w)
+		}
+	}
+	return subscribed, nil
+}
+
+func (r *mysqlRepository) findMany(ctx context.Context, q string, args ...interface{}) ([]webhook.Webhook, error) {
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	rows, err := r.client.QueryContext(ctx, q, args...)
+	if err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := make([]webhook.Webhook, 0)
+
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			r.logger.Error(err)
+			return nil, err
+		}
+
+		webhooks = append(webhooks, w)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+func (r *mysqlRepository) FindOne(ctx context.Context, ID string) (webhook.Webhook, error) {
+	q := `SELECT ` + webhookColumns + ` FROM webhook WHERE id = ?;`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	w, err := scanWebhook(r.client.QueryRowContext(ctx, q, ID))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			r.logger.Error(err)
+		}
+		return webhook.Webhook{}, err
+	}
+
+	return w, nil
+}
+
+func (r *mysqlRepository) Update(ctx context.Context, w webhook.Webhook) error {
+	q := `UPDATE webhook SET url = ?, secret = ?, event_types = ?, active = ? WHERE id = ?;`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	_, err := r.client.ExecContext(ctx, q, w.URL, w.Secret, strings.Join(w.EventTypes, ","), w.Active, w.ID)
+	if err != nil {
+		r.logger.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *mysqlRepository) Delete(ctx context.Context, ID string) error {
+	q := `DELETE FROM webhook WHERE id = ?;`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	_, err := r.client.ExecContext(ctx, q, ID)
+	if err != nil {
+		r.logger.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *mysqlRepository) CreateDeliveries(ctx context.Context, deliveries []webhook.Delivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	q := `INSERT INTO webhook_delivery (webhook_id, event_id, event_type, payload, status, attempts, next_attempt_at, created_at)
+	VALUES ` + strings.TrimSuffix(strings.Repeat("(?, ?, ?, ?, ?, ?, ?, ?), ", len(deliveries)), ", ") + `;`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	args := make([]interface{}, 0, 8*len(deliveries))
+	for _, d := range deliveries {
+		args = append(args, d.WebhookID, d.EventID, d.EventType, string(d.Payload), d.Status, d.Attempts,
+			d.NextAttemptAt, d.CreatedAt)
+	}
+
+	if _, err := r.client.ExecContext(ctx, q, args...); err != nil {
+		r.logger.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *mysqlRepository) FindDeliveries(ctx context.Context, f webhook.DeliveryFilter) ([]webhook.Delivery, error) {
+	var where []string
+	var args []interface{}
+	if f.WebhookID != 0 {
+		where, args = append(where, "webhook_id = ?"), append(args, f.WebhookID)
+	}
+	if f.Status != "" {
+		where, args = append(where, "status = ?"), append(args, f.Status)
+	}
+
+	limit := f.Limit
+	if limit <= 0 || limit > maxLimit {
+		limit = maxLimit
+	}
+
+	q := `SELECT ` + deliveryColumns + ` FROM webhook_delivery`
+	if len(where) > 0 {
+		q += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	q += fmt.Sprintf(` ORDER BY id DESC LIMIT %d;`, limit)
+
+	return r.findDeliveries(ctx, q, args...)
+}
+
+func (r *mysqlRepository) FindDue(ctx context.Context, now time.Time, limit int) ([]webhook.Delivery, error) {
+	q := `SELECT ` + deliveryColumns + ` FROM webhook_delivery WHERE status = ? AND next_attempt_at <= ?
+	ORDER BY next_attempt_at LIMIT ` + strconv.Itoa(limit) + `;`
+
+	return r.findDeliveries(ctx, q, webhook.StatusPending, now)
+}
+
+func (r *mysqlRepository) findDeliveries(ctx context.Context, q string, args ...interface{}) ([]webhook.Delivery, error) {
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	rows, err := r.client.QueryContext(ctx, q, args...)
+	if err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]webhook.Delivery, 0)
+
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			r.logger.Error(err)
+			return nil, err
+		}
+
+		deliveries = append(deliveries, d)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (r *mysqlRepository) FindDelivery(ctx context.Context, ID string) (webhook.Delivery, error) {
+	q := `SELECT ` + deliveryColumns + ` FROM webhook_delivery WHERE id = ?;`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	d, err := scanDelivery(r.client.QueryRowContext(ctx, q, ID))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			r.logger.Error(err)
+		}
+		return webhook.Delivery{}, err
+	}
+
+	return d, nil
+}
+
+func (r *mysqlRepository) Claim(ctx context.Context, d webhook.Delivery, until time.Time) (bool, error) {
+	q := `UPDATE webhook_delivery SET next_attempt_at = ? WHERE id = ? AND status = ? AND next_attempt_at = ?;`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	res, err := r.client.ExecContext(ctx, q, until, d.ID, webhook.StatusPending, d.NextAttemptAt)
+	if err != nil {
+		r.logger.Error(err)
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		r.logger.Error(err)
+		return false, err
+	}
+
+	return n == 1, nil
+}
+
+func (r *mysqlRepository) SaveAttempt(ctx context.Context, d webhook.Delivery) error {
+	q := `UPDATE webhook_delivery SET status = ?, attempts = ?, next_attempt_at = ?, last_status_code = ?, last_error = ?,
+	delivered_at = ? WHERE id = ?;`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	_, err := r.client.ExecContext(ctx, q, d.Status, d.Attempts, d.NextAttemptAt, d.LastStatusCode, d.LastError,
+		d.DeliveredAt, d.ID)
+	if err != nil {
+		r.logger.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanWebhook(row scanner) (webhook.Webhook, error) {
+	var w webhook.Webhook
+	var eventTypes string
+
+	if err := row.Scan(&w.ID, &w.URL, &w.Secret, &eventTypes, &w.Active, &w.CreatedAt); err != nil {
+		return webhook.Webhook{}, err
+	}
+
+	w.EventTypes = make([]string, 0)
+	if eventTypes != "" {
+		w.EventTypes = strings.Split(eventTypes, ",")
+	}
+
+	return w, nil
+}
+
+func scanDelivery(row scanner) (webhook.Delivery, error) {
+	var d webhook.Delivery
+	var payload []byte
+	var statusCode sql.NullInt64
+	var deliveredAt sql.NullTime
+
+	err := row.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &payload, &d.Status, &d.Attempts, &d.NextAttemptAt,
+		&statusCode, &d.LastError, &d.CreatedAt, &deliveredAt)
+	if err != nil {
+		return webhook.Delivery{}, err
+	}
+
+	d.Payload = payload
+	if statusCode.Valid {
+		code := int(statusCode.Int64)
+		d.LastStatusCode = &code
+	}
+	if deliveredAt.Valid {
+		d.DeliveredAt = &deliveredAt.Time
+	}
+
+	return d, nil
+}
+
+func NewMySQLRepository(client mysql.Client, logger *logging.Logger) webhook.Repository {
+	return &mysqlRepository{
+		client: client,
+		logger: logger,
+	}
+}
@@ -0,0 +1,247 @@
+package webhook
+
+import (
+	"awesome-clean-arch/internal/events"
+	"awesome-clean-arch/pkg/httpsign"
+	"awesome-clean-arch/pkg/logging"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Headers of a delivery. The signature is the hex HMAC-SHA256, keyed with
+// the webhook secret, of the timestamp, a dot and the body.
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderEventID   = "X-Webhook-Event-Id"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Options tune the delivery worker.
+type Options struct {
+	// MaxAttempts is the number of attempts before a delivery is dead.
+	MaxAttempts int
+	// Backoff is the delay before the first retry, doubled for each further
+	// retry up to MaxBackoff.
+	Backoff      time.Duration
+	MaxBackoff   time.Duration
+	Timeout      time.Duration
+	PollInterval time.Duration
+	Concurrency  int
+}
+
+// Dispatcher stores a delivery per subscribed webhook for every event and
+// sends them in the background, so a slow receiver never delays a request.
+type Dispatcher struct {
+	repository Repository
+	options    Options
+	client     *http.Client
+	logger     *logging.Logger
+	wake       chan struct{}
+}
+
+func NewDispatcher(repository Repository, options Options, logger *logging.Logger) *Dispatcher {
+	return &Dispatcher{
+		repository: repository,
+		options:    options,
+		client:     &http.Client{Timeout: options.Timeout},
+		logger:     logger,
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+// Enqueue stores the deliveries of e, identified by e.ID. An event enqueued
+// twice is delivered twice with the same X-Webhook-Event-Id, receivers
+// deduplicate on it.
+func (d *Dispatcher) Enqueue(ctx context.Context, e events.Event) error {
+	webhooks, err := d.repository.FindActive(ctx, e.Type)
+	if err != nil {
+		return err
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	deliveries := make([]Delivery, 0, len(webhooks))
+	for _, w := range webhooks {
+		deliveries = append(deliveries, Delivery{
+			WebhookID:     w.ID,
+			EventID:       strconv.FormatUint(e.ID, 10),
+			EventType:     e.Type,
+			Payload:       payload,
+			Status:        StatusPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+	}
+	if err = d.repository.CreateDeliveries(ctx, deliveries); err != nil {
+		return err
+	}
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run sends due deliveries every poll interval, or as soon as new ones are
+// enqueued, until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.options.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.dispatch(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+func (d *Dispatcher) dispatch(ctx context.Context) {
+	for ctx.Err() == nil {
+		now := time.Now().UTC()
+		due, err := d.repository.FindDue(ctx, now, d.options.Concurrency)
+		if err != nil || len(due) == 0 {
+			return
+		}
+
+		var wg sync.WaitGroup
+		claimedAny := false
+		for _, delivery := range due {
+			// Claiming for longer than an attempt may take keeps other
+			// instances from sending the same delivery concurrently.
+			claimed, err := d.repository.Claim(ctx, delivery, now.Add(2*d.options.Timeout))
+			if err != nil || !claimed {
+				continue
+			}
+			claimedAny = true
+			wg.Add(1)
+			go func(delivery Delivery) {
+				defer wg.Done()
+				d.attempt(ctx, delivery)
+			}(delivery)
+		}
+		wg.Wait()
+		if !claimedAny {
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, delivery Delivery) {
+	w, err := d.repository.FindOne(ctx, strconv.Itoa(delivery.WebhookID))
+	if err != nil {
+		d.logger.Errorf("webhook: delivery %d: %s", delivery.ID, err)
+		return
+	}
+
+	// Deliveries of an inactive webhook wait for it to be activated again.
+	if !w.Active {
+		delivery.NextAttemptAt = time.Now().UTC().Add(d.options.MaxBackoff)
+		delivery.LastError = "webhook inactive"
+		if err = d.repository.SaveAttempt(context.Background(), delivery); err != nil {
+			d.logger.Errorf("webhook: failed to save delivery %d: %s", delivery.ID, err)
+		}
+		return
+	}
+
+	delivery.Attempts++
+	statusCode, err := d.send(ctx, w, delivery)
+	now := time.Now().UTC()
+	delivery.LastStatusCode, delivery.LastError = nil, ""
+	if statusCode != 0 {
+		delivery.LastStatusCode = &statusCode
+	}
+
+	switch {
+	case err == nil:
+		delivery.Status = StatusDelivered
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= d.options.MaxAttempts:
+		delivery.Status = StatusDead
+		delivery.LastError = errorText(err)
+		d.logger.Warnf("webhook: delivery %d of %s to webhook %d is dead after %d attempts: %s",
+			delivery.ID, delivery.EventType, w.ID, delivery.Attempts, err)
+	default:
+		delivery.LastError = errorText(err)
+		delivery.NextAttemptAt = now.Add(d.backoff(delivery.Attempts))
+	}
+
+	// The outcome is saved even when ctx was cancelled during the attempt.
+	if err = d.repository.SaveAttempt(context.Background(), delivery); err != nil {
+		d.logger.Errorf("webhook: failed to save delivery %d: %s", delivery.ID, err)
+	}
+}
+
+// send posts the payload, any response but 2xx is a failure.
+func (d *Dispatcher) send(ctx context.Context, w Webhook, delivery Delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "awesome-clean-arch-webhook")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderEventID, delivery.EventID)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign([]byte(w.Secret), timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver answered %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.options.Backoff
+	for i := 1; i < attempts && delay < d.options.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > d.options.MaxBackoff {
+		delay = d.options.MaxBackoff
+	}
+	return delay
+}
+
+// errorText fits err into the last_error column.
+func errorText(err error) string {
+	text := err.Error()
+	if len(text) > 1024 {
+		text = text[:1024]
+	}
+	return text
+}
+
+// Sign returns the signature receivers compare with X-Webhook-Signature.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	return "sha256=" + httpsign.Signature(secret, timestamp+"."+string(body))
+}
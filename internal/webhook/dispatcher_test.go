@@ -0,0 +1,383 @@
+package webhook
+
+import (
+	"awesome-clean-arch/internal/events"
+	"awesome-clean-arch/pkg/logging"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryRepository keeps webhooks and deliveries in memory, it implements
+// what the dispatcher uses.
+type memoryRepository struct {
+	mu         sync.Mutex
+	webhooks   map[int]Webhook
+	deliveries []Delivery
+}
+
+func newMemoryRepository(webhooks ...Webhook) *memoryRepository {
+	r := &memoryRepository{webhooks: make(map[int]Webhook)}
+	for _, w := range webhooks {
+		r.webhooks[w.ID] = w
+	}
+	return r
+}
+
+func (r *memoryRepository) Create(ctx context.Context, w Webhook) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	w.ID = len(r.webhooks) + 1
+	r.webhooks[w.ID] = w
+	return strconv.Itoa(w.ID), nil
+}
+
+func (r *memoryRepository) FindAll(ctx context.Context) ([]Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	all := make([]Webhook, 0, len(r.webhooks))
+	for _, w := range r.webhooks {
+		all = append(all, w)
+	}
+	return all, nil
+}
+
+func (r *memoryRepository) FindOne(ctx context.Context, ID string) (Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id, _ := strconv.Atoi(ID)
+	w, ok := r.webhooks[id]
+	if !ok {
+		return Webhook{}, sql.ErrNoRows
+	}
+	return w, nil
+}
+
+func (r *memoryRepository) FindActive(ctx context.Context, eventType string) ([]Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var active []Webhook
+	for _, w := range r.webhooks {
+		if w.Active && w.Subscribed(eventType) {
+			active = append(active, w)
+		}
+	}
+	return active, nil
+}
+
+func (r *memoryRepository) Update(ctx context.Context, w Webhook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.webhooks[w.ID] = w
+	return nil
+}
+
+func (r *memoryRepository) Delete(ctx context.Context, ID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id, _ := strconv.Atoi(ID)
+	delete(r.webhooks, id)
+	return nil
+}
+
+func (r *memoryRepository) CreateDeliveries(ctx context.Context, deliveries []Delivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, d := range deliveries {
+		d.ID = int64(len(r.deliveries) + 1)
+		r.deliveries = append(r.deliveries, d)
+	}
+	return nil
+}
+
+func (r *memoryRepository) FindDeliveries(ctx context.Context, filter DeliveryFilter) ([]Delivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Delivery(nil), r.deliveries...), nil
+}
+
+func (r *memoryRepository) FindDelivery(ctx context.Context, ID string) (Delivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id, _ := strconv.ParseInt(ID, 10, 64)
+	for _, d := range r.deliveries {
+		if d.ID == id {
+			return d, nil
+		}
+	}
+	return Delivery{}, sql.ErrNoRows
+}
+
+func (r *memoryRepository) FindDue(ctx context.Context, now time.Time, limit int) ([]Delivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var due []Delivery
+	for _, d := range r.deliveries {
+		if d.Status == StatusPending && !d.NextAttemptAt.After(now) && len(due) < limit {
+			due = append(due, d)
+		}
+	}
+	return due, nil
+}
+
+func (r *memoryRepository) Claim(ctx context.Context, d Delivery, until time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := &r.deliveries[d.ID-1]
+	if !stored.NextAttemptAt.Equal(d.NextAttemptAt) {
+		return false, nil
+	}
+	stored.NextAttemptAt = until
+	return true, nil
+}
+
+func (r *memoryRepository) SaveAttempt(ctx context.Context, d Delivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deliveries[d.ID-1] = d
+	return nil
+}
+
+// makeDue lets the pending deliveries be attempted again right away.
+func (r *memoryRepository) makeDue() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.deliveries {
+		r.deliveries[i].NextAttemptAt = time.Time{}
+	}
+}
+
+func (r *memoryRepository) delivery(t *testing.T) Delivery {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.deliveries) != 1 {
+		t.Fatalf("got %d deliveries, want 1", len(r.deliveries))
+	}
+	return r.deliveries[0]
+}
+
+var testOptions = Options{
+	MaxAttempts:  3,
+	Backoff:      time.Second,
+	MaxBackoff:   time.Minute,
+	Timeout:      time.Second,
+	PollInterval: time.Second,
+	Concurrency:  2,
+}
+
+var testEvent = events.Event{
+	ID:         42,
+	Type:       "user.update",
+	EntityType: "user",
+	EntityID:   "7",
+	Action:     "update",
+	Time:       time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+}
+
+// receiver answers the statuses in turn, then the last one, and records
+// the requests it got.
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	rc.mu.Lock()
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+	status := rc.statuses[0]
+	if len(rc.statuses) > 1 {
+		rc.statuses = rc.statuses[1:]
+	}
+	rc.mu.Unlock()
+
+	w.WriteHeader(status)
+}
+
+func newTestDispatcher(t *testing.T, options Options, statuses ...int) (*Dispatcher, *memoryRepository, *receiver) {
+	t.Helper()
+	rc := &receiver{statuses: statuses}
+	server := httptest.NewServer(rc)
+	t.Cleanup(server.Close)
+
+	repo := newMemoryRepository(Webhook{
+		ID:         1,
+		URL:        server.URL,
+		Secret:     "s3cret",
+		EventTypes: []string{"user.update"},
+		Active:     true,
+	})
+	d := NewDispatcher(repo, options, logging.GetLogger())
+	if err := d.Enqueue(context.Background(), testEvent); err != nil {
+		t.Fatal(err)
+	}
+	return d, repo, rc
+}
+
+func TestDeliveryIsSigned(t *testing.T) {
+	d, repo, rc := newTestDispatcher(t, testOptions, http.StatusOK)
+	d.dispatch(context.Background())
+
+	if len(rc.requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(rc.requests))
+	}
+	req, body := rc.requests[0], rc.bodies[0]
+
+	timestamp := req.Header.Get(HeaderTimestamp)
+	if _, err := strconv.ParseInt(timestamp, 10, 64); err != nil {
+		t.Fatalf("%s %q is not a unix time", HeaderTimestamp, timestamp)
+	}
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(timestamp + "." + string(body)))
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); req.Header.Get(HeaderSignature) != want {
+		t.Errorf("%s = %q, want %q", HeaderSignature, req.Header.Get(HeaderSignature), want)
+	}
+
+	delivery := repo.delivery(t)
+	for header, want := range map[string]string{
+		HeaderEvent:    "user.update",
+		HeaderEventID:  "42",
+		HeaderDelivery: strconv.FormatInt(delivery.ID, 10),
+		"Content-Type": "application/json",
+	} {
+		if got := req.Header.Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+	if string(body) != string(delivery.Payload) {
+		t.Errorf("body = %s, want the stored payload %s", body, delivery.Payload)
+	}
+}
+
+func TestDeliveryOutcome(t *testing.T) {
+	tests := []struct {
+		status     int
+		wantStatus string
+	}{
+		{http.StatusOK, StatusDelivered},
+		{http.StatusAccepted, StatusDelivered},
+		{http.StatusNoContent, StatusDelivered},
+		{http.StatusBadRequest, StatusPending},
+		{http.StatusGone, StatusPending},
+		{http.StatusInternalServerError, StatusPending},
+		{http.StatusServiceUnavailable, StatusPending},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.status), func(t *testing.T) {
+			d, repo, _ := newTestDispatcher(t, testOptions, tt.status)
+			start := time.Now().UTC()
+			d.dispatch(context.Background())
+
+			delivery := repo.delivery(t)
+			if delivery.Status != tt.wantStatus {
+				t.Fatalf("status = %s, want %s", delivery.Status, tt.wantStatus)
+			}
+			if delivery.Attempts != 1 {
+				t.Errorf("attempts = %d, want 1", delivery.Attempts)
+			}
+			if delivery.LastStatusCode == nil || *delivery.LastStatusCode != tt.status {
+				t.Errorf("last status code = %v, want %d", delivery.LastStatusCode, tt.status)
+			}
+
+			if tt.wantStatus == StatusDelivered {
+				if delivery.DeliveredAt == nil || delivery.LastError != "" {
+					t.Errorf("delivered at = %v, last error = %q", delivery.DeliveredAt, delivery.LastError)
+				}
+				return
+			}
+			if delivery.DeliveredAt != nil || delivery.LastError == "" {
+				t.Errorf("delivered at = %v, last error = %q", delivery.DeliveredAt, delivery.LastError)
+			}
+			if retry := delivery.NextAttemptAt.Sub(start); retry < testOptions.Backoff || retry > testOptions.Backoff+time.Second {
+				t.Errorf("next attempt in %s, want %s", retry, testOptions.Backoff)
+			}
+		})
+	}
+}
+
+func TestDeliveryIsRetriedUntilDelivered(t *testing.T) {
+	d, repo, rc := newTestDispatcher(t, testOptions, http.StatusInternalServerError, http.StatusOK)
+
+	d.dispatch(context.Background())
+	// Not due yet.
+	d.dispatch(context.Background())
+	if len(rc.requests) != 1 {
+		t.Fatalf("got %d requests before the backoff elapsed, want 1", len(rc.requests))
+	}
+
+	repo.makeDue()
+	d.dispatch(context.Background())
+
+	delivery := repo.delivery(t)
+	if delivery.Status != StatusDelivered || delivery.Attempts != 2 {
+		t.Errorf("status = %s after %d attempts, want %s after 2", delivery.Status, delivery.Attempts, StatusDelivered)
+	}
+	if rc.requests[0].Header.Get(HeaderEventID) != rc.requests[1].Header.Get(HeaderEventID) {
+		t.Error("a retry must keep the event id")
+	}
+}
+
+func TestDeliveryIsDeadAfterMaxAttempts(t *testing.T) {
+	d, repo, rc := newTestDispatcher(t, testOptions, http.StatusInternalServerError)
+
+	for i := 0; i < testOptions.MaxAttempts+2; i++ {
+		d.dispatch(context.Background())
+		repo.makeDue()
+	}
+
+	if len(rc.requests) != testOptions.MaxAttempts {
+		t.Errorf("got %d requests, want %d", len(rc.requests), testOptions.MaxAttempts)
+	}
+	delivery := repo.delivery(t)
+	if delivery.Status != StatusDead {
+		t.Errorf("status = %s, want %s", delivery.Status, StatusDead)
+	}
+	if delivery.Attempts != testOptions.MaxAttempts {
+		t.Errorf("attempts = %d, want %d", delivery.Attempts, testOptions.MaxAttempts)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := NewDispatcher(newMemoryRepository(), Options{Backoff: time.Second, MaxBackoff: 10 * time.Second}, logging.GetLogger())
+
+	for attempts, want := range map[int]time.Duration{
+		1:  time.Second,
+		2:  2 * time.Second,
+		3:  4 * time.Second,
+		4:  8 * time.Second,
+		5:  10 * time.Second,
+		20: 10 * time.Second,
+	} {
+		if got := d.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
+
+func TestEnqueueSkipsUnsubscribedWebhooks(t *testing.T) {
+	repo := newMemoryRepository(
+		Webhook{ID: 1, URL: "http://127.0.0.1", EventTypes: []string{"profile.create"}, Active: true},
+		Webhook{ID: 2, URL: "http://127.0.0.1", EventTypes: []string{"user.update"}, Active: false},
+	)
+	d := NewDispatcher(repo, testOptions, logging.GetLogger())
+	if err := d.Enqueue(context.Background(), testEvent); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.deliveries) != 0 {
+		t.Errorf("got %d deliveries, want none", len(repo.deliveries))
+	}
+}
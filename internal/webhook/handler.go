@@ -0,0 +1,268 @@
+package webhook
+
+import (
+	"awesome-clean-arch/internal/auth"
+	"awesome-clean-arch/internal/handlers"
+	"awesome-clean-arch/pkg/logging"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	webhooksURL   = "/webhooks"
+	webhookURL    = "/webhooks/:id"
+	deliveriesURL = "/webhooks/:id/deliveries"
+	redeliverURL  = "/webhooks/:id/deliveries/:delivery_id/redeliver"
+)
+
+var _ handlers.Handler = &handler{}
+
+type CreateWebhookRequest struct {
+	URL        string   `json:"url"`
+	Secret     string   `json:"secret,omitempty"`
+	EventTypes []string `json:"event_types"`
+	// Active defaults to true.
+	Active *bool `json:"active,omitempty"`
+}
+
+type handler struct {
+	logger     *logging.Logger
+	repository Repository
+}
+
+func NewHandler(logger *logging.Logger, repository Repository) handlers.Handler {
+	return &handler{
+		logger:     logger,
+		repository: repository,
+	}
+}
+
+func (h *handler) Middleware() []handlers.Middleware {
+	return []handlers.Middleware{auth.RequireScope(auth.ScopeWebhooksManage)}
+}
+
+func (h *handler) Register(router handlers.Router) {
+	router.GET(webhooksURL, h.GetWebhooksList)
+	// The response carries the signing secret, it must not be kept for replay.
+	router.With(handlers.NoReplay).POST(webhooksURL, h.CreateWebhook)
+	router.GET(webhookURL, h.GetWebhook)
+	router.PUT(webhookURL, h.UpdateWebhook)
+	router.DELETE(webhookURL, h.DeleteWebhook)
+	router.GET(deliveriesURL, h.GetDeliveries)
+	router.POST(redeliverURL, h.Redeliver)
+}
+
+func (h *handler) Docs() []handlers.Doc {
+	tags := []string{"webhooks"}
+	scope := auth.ScopeWebhooksManage
+	return []handlers.Doc{
+		{Method: http.MethodGet, Path: webhooksURL, Summary: "List webhooks", Tags: tags, Scope: scope, Response: []Webhook{}},
+		{Method: http.MethodPost, Path: webhooksURL, Summary: "Create a webhook", Tags: tags, Scope: scope,
+			Description: "The secret is generated when empty and only returned here. Deliveries carry " +
+				HeaderSignature + ", sha256= and the hex HMAC-SHA256 of " + HeaderTimestamp + ", a dot and the body.",
+			Request: CreateWebhookRequest{}, Response: map[string]string{}, Status: http.StatusCreated},
+		{Method: http.MethodGet, Path: webhookURL, Summary: "Get a webhook", Tags: tags, Scope: scope, Response: Webhook{}},
+		{Method: http.MethodPut, Path: webhookURL, Summary: "Update a webhook", Tags: tags, Scope: scope,
+			Request: Webhook{}, Response: Webhook{}},
+		{Method: http.MethodDelete, Path: webhookURL, Summary: "Delete a webhook and its deliveries", Tags: tags, Scope: scope,
+			Status: http.StatusNoContent},
+		{Method: http.MethodGet, Path: deliveriesURL, Summary: "List the deliveries of a webhook, newest first", Tags: tags,
+			Scope: scope, Response: []Delivery{}, Query: []handlers.Param{
+				{Name: "status", Description: "pending, delivered or dead"},
+				{Name: "limit", Description: "At most 1000"},
+			}},
+		{Method: http.MethodPost, Path: redeliverURL, Summary: "Send a delivery again", Tags: tags, Scope: scope,
+			Description: "Resets the attempts of the delivery, typically a dead one.", Response: Delivery{}},
+	}
+}
+
+func (h *handler) GetWebhooksList(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	all, err := h.repository.FindAll(r.Context())
+	if err != nil {
+		handlers.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	for i := range all {
+		all[i] = all[i].Masked()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(all)
+}
+
+func (h *handler) GetWebhook(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	wh, ok := h.find(w, r, params.ByName("id"))
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(wh.Masked())
+}
+
+func (h *handler) CreateWebhook(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	var req CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handlers.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	wh := Webhook{URL: req.URL, Secret: req.Secret, EventTypes: req.EventTypes, Active: req.Active == nil || *req.Active}
+	if err := wh.Validate(); err != nil {
+		handlers.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if wh.Secret == "" {
+		secret, err := generateSecret()
+		if err != nil {
+			handlers.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		wh.Secret = secret
+	}
+
+	id, err := h.repository.Create(r.Context(), wh)
+	if err != nil {
+		handlers.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{"id": id, "secret": wh.Secret})
+}
+
+// UpdateWebhook replaces the fields present in the body. The secret is kept
+// when it is omitted or masked.
+func (h *handler) UpdateWebhook(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	current, ok := h.find(w, r, params.ByName("id"))
+	if !ok {
+		return
+	}
+
+	wh := current
+	if err := json.NewDecoder(r.Body).Decode(&wh); err != nil {
+		handlers.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if wh.Secret == "" || wh.Secret == current.Masked().Secret {
+		wh.Secret = current.Secret
+	}
+	wh.ID, wh.CreatedAt = current.ID, current.CreatedAt
+	if err := wh.Validate(); err != nil {
+		handlers.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.repository.Update(r.Context(), wh); err != nil {
+		handlers.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(wh.Masked())
+}
+
+func (h *handler) DeleteWebhook(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	if _, ok := h.find(w, r, params.ByName("id")); !ok {
+		return
+	}
+
+	if err := h.repository.Delete(r.Context(), params.ByName("id")); err != nil {
+		handlers.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler) GetDeliveries(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	wh, ok := h.find(w, r, params.ByName("id"))
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	filter := DeliveryFilter{WebhookID: wh.ID, Status: query.Get("status")}
+	switch filter.Status {
+	case "", StatusPending, StatusDelivered, StatusDead:
+	default:
+		handlers.WriteError(w, http.StatusBadRequest, "status must be pending, delivered or dead")
+		return
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			handlers.WriteError(w, http.StatusBadRequest, "limit must be a positive number")
+			return
+		}
+		filter.Limit = limit
+	}
+
+	deliveries, err := h.repository.FindDeliveries(r.Context(), filter)
+	if err != nil {
+		handlers.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+func (h *handler) Redeliver(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	d, err := h.repository.FindDelivery(r.Context(), params.ByName("delivery_id"))
+	if err != nil || strconv.Itoa(d.WebhookID) != params.ByName("id") {
+		if err == nil || err == sql.ErrNoRows {
+			handlers.WriteError(w, http.StatusNotFound, "delivery not found")
+			return
+		}
+		handlers.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if d.Status == StatusPending {
+		handlers.WriteError(w, http.StatusConflict, "delivery is still pending")
+		return
+	}
+
+	d.Status, d.Attempts, d.NextAttemptAt, d.DeliveredAt = StatusPending, 0, time.Now().UTC(), nil
+	if err = h.repository.SaveAttempt(r.Context(), d); err != nil {
+		handlers.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(d)
+}
+
+func (h *handler) find(w http.ResponseWriter, r *http.Request, id string) (Webhook, bool) {
+	wh, err := h.repository.FindOne(r.Context(), id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			handlers.WriteError(w, http.StatusNotFound, "webhook not found")
+			return Webhook{}, false
+		}
+		handlers.WriteError(w, http.StatusInternalServerError, err.Error())
+		return Webhook{}, false
+	}
+	return wh, true
+}
+
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
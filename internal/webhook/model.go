@@ -0,0 +1,100 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Delivery statuses. Pending deliveries are retried until they succeed or
+// run out of attempts and become dead.
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusDead      = "dead"
+)
+
+// EventTypes are the events a webhook can subscribe to, entity type and
+// action of the change.
+var EventTypes = []string{
+	"user.create", "user.update", "user.delete", "user.restore",
+	"profile.create", "profile.update", "profile.delete", "profile.restore",
+	"user_data.create", "user_data.update", "user_data.delete",
+}
+
+type Webhook struct {
+	ID  int    `json:"id"`
+	URL string `json:"url"`
+	// Secret signs the payloads, it is generated when empty on creation.
+	Secret     string    `json:"secret,omitempty"`
+	EventTypes []string  `json:"event_types"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (w Webhook) Validate() error {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url: %q is not an absolute http or https URL", w.URL)
+	}
+	if len(w.EventTypes) == 0 {
+		return fmt.Errorf("event_types: at least one is required")
+	}
+	for _, t := range w.EventTypes {
+		if !isEventType(t) {
+			return fmt.Errorf("event_types: unknown event type %q", t)
+		}
+	}
+	return nil
+}
+
+func (w Webhook) Subscribed(eventType string) bool {
+	for _, t := range w.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Masked hides the secret like API keys are hidden.
+func (w Webhook) Masked() Webhook {
+	if w.Secret != "" {
+		w.Secret = "****"
+	}
+	return w
+}
+
+// Delivery is one event sent to one webhook, with the outcome of its last
+// attempt.
+type Delivery struct {
+	ID             int64           `json:"id"`
+	WebhookID      int             `json:"webhook_id"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	LastStatusCode *int            `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// DeliveryFilter narrows the delivery history, zero values match everything.
+type DeliveryFilter struct {
+	WebhookID int
+	Status    string
+	Limit     int
+}
+
+func isEventType(t string) bool {
+	for _, known := range EventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
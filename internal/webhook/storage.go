@@ -0,0 +1,30 @@
+package webhook
+
+import (
+	"context"
+	"time"
+)
+
+type Repository interface {
+	Create(ctx context.Context, w Webhook) (string, error)
+	FindAll(ctx context.Context) ([]Webhook, error)
+	FindOne(ctx context.Context, ID string) (Webhook, error)
+	// FindActive lists the active webhooks subscribed to eventType.
+	FindActive(ctx context.Context, eventType string) ([]Webhook, error)
+	Update(ctx context.Context, w Webhook) error
+	Delete(ctx context.Context, ID string) error
+
+	CreateDeliveries(ctx context.Context, deliveries []Delivery) error
+	FindDeliveries(ctx context.Context, filter DeliveryFilter) ([]Delivery, error)
+	FindDelivery(ctx context.Context, ID string) (Delivery, error)
+	// FindDue lists pending deliveries whose next attempt is due at now,
+	// oldest first.
+	FindDue(ctx context.Context, now time.Time, limit int) ([]Delivery, error)
+	// Claim moves the next attempt of a due delivery to until, so no other
+	// worker picks it up meanwhile. claimed is false when it was not due
+	// anymore.
+	Claim(ctx context.Context, d Delivery, until time.Time) (claimed bool, err error)
+	// SaveAttempt stores the outcome of an attempt: status, attempts, next
+	// attempt, last status code and error, and delivery time.
+	SaveAttempt(ctx context.Context, d Delivery) error
+}
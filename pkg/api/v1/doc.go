@@ -0,0 +1,5 @@
+// Package apiv1 holds the gRPC services and messages generated from
+// api/proto/v1.
+package apiv1
+
+//go:generate protoc -I ../../../api/proto --go_out=.. --go_opt=paths=source_relative --go-grpc_out=.. --go-grpc_opt=paths=source_relative v1/user.proto v1/profile.proto v1/user_data.proto v1/auth.proto
@@ -0,0 +1,258 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: v1/user_data.proto
+
+package apiv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// UserDataServiceClient is the client API for UserDataService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type UserDataServiceClient interface {
+	// Requires scope user_data:write.
+	CreateUserData(ctx context.Context, in *CreateUserDataRequest, opts ...grpc.CallOption) (*CreateUserDataResponse, error)
+	// Requires scope user_data:read.
+	ListUserData(ctx context.Context, in *ListUserDataRequest, opts ...grpc.CallOption) (*ListUserDataResponse, error)
+	GetUserData(ctx context.Context, in *GetUserDataRequest, opts ...grpc.CallOption) (*UserData, error)
+	// Requires scope user_data:write. The version must equal the stored one.
+	UpdateUserData(ctx context.Context, in *UpdateUserDataRequest, opts ...grpc.CallOption) (*UserData, error)
+	// Requires scope user_data:write.
+	DeleteUserData(ctx context.Context, in *DeleteUserDataRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+}
+
+type userDataServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewUserDataServiceClient(cc grpc.ClientConnInterface) UserDataServiceClient {
+	return &userDataServiceClient{cc}
+}
+
+func (c *userDataServiceClient) CreateUserData(ctx context.Context, in *CreateUserDataRequest, opts ...grpc.CallOption) (*CreateUserDataResponse, error) {
+	out := new(CreateUserDataResponse)
+	err := c.cc.Invoke(ctx, "/awesome.v1.UserDataService/CreateUserData", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userDataServiceClient) ListUserData(ctx context.Context, in *ListUserDataRequest, opts ...grpc.CallOption) (*ListUserDataResponse, error) {
+	out := new(ListUserDataResponse)
+	err := c.cc.Invoke(ctx, "/awesome.v1.UserDataService/ListUserData", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userDataServiceClient) GetUserData(ctx context.Context, in *GetUserDataRequest, opts ...grpc.CallOption) (*UserData, error) {
+	out := new(UserData)
+	err := c.cc.Invoke(ctx, "/awesome.v1.UserDataService/GetUserData", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userDataServiceClient) UpdateUserData(ctx context.Context, in *UpdateUserDataRequest, opts ...grpc.CallOption) (*UserData, error) {
+	out := new(UserData)
+	err := c.cc.Invoke(ctx, "/awesome.v1.UserDataService/UpdateUserData", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userDataServiceClient) DeleteUserData(ctx context.Context, in *DeleteUserDataRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/awesome.v1.UserDataService/DeleteUserData", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UserDataServiceServer is the server API for UserDataService service.
+// All implementations must embed UnimplementedUserDataServiceServer
+// for forward compatibility
+type UserDataServiceServer interface {
+	// Requires scope user_data:write.
+	CreateUserData(context.Context, *CreateUserDataRequest) (*CreateUserDataResponse, error)
+	// Requires scope user_data:read.
+	ListUserData(context.Context, *ListUserDataRequest) (*ListUserDataResponse, error)
+	GetUserData(context.Context, *GetUserDataRequest) (*UserData, error)
+	// Requires scope user_data:write. The version must equal the stored one.
+	UpdateUserData(context.Context, *UpdateUserDataRequest) (*UserData, error)
+	// Requires scope user_data:write.
+	DeleteUserData(context.Context, *DeleteUserDataRequest) (*emptypb.Empty, error)
+	mustEmbedUnimplementedUserDataServiceServer()
+}
+
+// UnimplementedUserDataServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedUserDataServiceServer struct {
+}
+
+func (UnimplementedUserDataServiceServer) CreateUserData(context.Context, *CreateUserDataRequest) (*CreateUserDataResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateUserData not implemented")
+}
+func (UnimplementedUserDataServiceServer) ListUserData(context.Context, *ListUserDataRequest) (*ListUserDataResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListUserData not implemented")
+}
+func (UnimplementedUserDataServiceServer) GetUserData(context.Context, *GetUserDataRequest) (*UserData, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUserData not implemented")
+}
+func (UnimplementedUserDataServiceServer) UpdateUserData(context.Context, *UpdateUserDataRequest) (*UserData, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateUserData not implemented")
+}
+func (UnimplementedUserDataServiceServer) DeleteUserData(context.Context, *DeleteUserDataRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteUserData not implemented")
+}
+func (UnimplementedUserDataServiceServer) mustEmbedUnimplementedUserDataServiceServer() {}
+
+// UnsafeUserDataServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to UserDataServiceServer will
+// result in compilation errors.
+type UnsafeUserDataServiceServer interface {
+	mustEmbedUnimplementedUserDataServiceServer()
+}
+
+func RegisterUserDataServiceServer(s grpc.ServiceRegistrar, srv UserDataServiceServer) {
+	s.RegisterService(&UserDataService_ServiceDesc, srv)
+}
+
+func _UserDataService_CreateUserData_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateUserDataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserDataServiceServer).CreateUserData(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/awesome.v1.UserDataService/CreateUserData",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserDataServiceServer).CreateUserData(ctx, req.(*CreateUserDataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserDataService_ListUserData_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListUserDataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserDataServiceServer).ListUserData(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/awesome.v1.UserDataService/ListUserData",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserDataServiceServer).ListUserData(ctx, req.(*ListUserDataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserDataService_GetUserData_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserDataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserDataServiceServer).GetUserData(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/awesome.v1.UserDataService/GetUserData",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserDataServiceServer).GetUserData(ctx, req.(*GetUserDataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserDataService_UpdateUserData_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateUserDataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserDataServiceServer).UpdateUserData(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/awesome.v1.UserDataService/UpdateUserData",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserDataServiceServer).UpdateUserData(ctx, req.(*UpdateUserDataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserDataService_DeleteUserData_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteUserDataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserDataServiceServer).DeleteUserData(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/awesome.v1.UserDataService/DeleteUserData",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserDataServiceServer).DeleteUserData(ctx, req.(*DeleteUserDataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// UserDataService_ServiceDesc is the grpc.ServiceDesc for UserDataService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var UserDataService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "awesome.v1.UserDataService",
+	HandlerType: (*UserDataServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateUserData",
+			Handler:    _UserDataService_CreateUserData_Handler,
+		},
+		{
+			MethodName: "ListUserData",
+			Handler:    _UserDataService_ListUserData_Handler,
+		},
+		{
+			MethodName: "GetUserData",
+			Handler:    _UserDataService_GetUserData_Handler,
+		},
+		{
+			MethodName: "UpdateUserData",
+			Handler:    _UserDataService_UpdateUserData_Handler,
+		},
+		{
+			MethodName: "DeleteUserData",
+			Handler:    _UserDataService_DeleteUserData_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "v1/user_data.proto",
+}
@@ -6,10 +6,12 @@ import (
 	"awesome-clean-arch/pkg/utils"
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	driver "github.com/go-sql-driver/mysql"
 )
 
 type Client interface {
@@ -21,7 +23,7 @@ type Client interface {
 
 func NewClient(ctx context.Context, maxAttempts int, sc config.StorageConfig) (*sql.DB, error) {
 	logger := logging.GetLogger()
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", sc.Username, sc.Password, sc.Host, sc.Port, sc.Database)
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true", sc.Username, sc.Password, sc.Host, sc.Port, sc.Database)
 	db, err := sql.Open("mysql", dsn)
 	if err != nil {
 		return nil, err
@@ -45,3 +47,19 @@ func NewClient(ctx context.Context, maxAttempts int, sc config.StorageConfig) (*
 
 	return db, nil
 }
+
+// IsDuplicateEntry reports whether err is a unique key violation.
+func IsDuplicateEntry(err error) bool {
+	var mysqlErr *driver.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
+}
+
+// In returns the placeholders and arguments of an IN (...) clause matching
+// values, which must not be empty.
+func In(values []string) (string, []interface{}) {
+	args := make([]interface{}, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	return strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", "), args
+}
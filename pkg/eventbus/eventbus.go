@@ -0,0 +1,177 @@
+package eventbus
+
+import (
+	"awesome-clean-arch/pkg/logging"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Handler reacts to an event of type E.
+type Handler[E any] func(ctx context.Context, event E) error
+
+type subscriber struct {
+	event  string
+	name   string
+	async  bool
+	handle func(ctx context.Context, event interface{}) error
+
+	mu    sync.Mutex
+	stats Stats
+}
+
+// Stats are the calls, failures and latency of one subscriber.
+type Stats struct {
+	Event        string        `json:"event"`
+	Subscriber   string        `json:"subscriber"`
+	Async        bool          `json:"async"`
+	Calls        uint64        `json:"calls"`
+	Errors       uint64        `json:"errors"`
+	Panics       uint64        `json:"panics"`
+	TotalLatency time.Duration `json:"total_latency_ns"`
+	MaxLatency   time.Duration `json:"max_latency_ns"`
+}
+
+// Bus dispatches typed events to the subscribers of their type, in the
+// order they subscribed. Synchronous subscribers run before Publish returns,
+// asynchronous ones in their own goroutine. A subscriber that fails or
+// panics is logged and counted, it never affects the other subscribers.
+// Publish returns the failures of the synchronous ones.
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[reflect.Type][]*subscriber
+	logger      *logging.Logger
+	running     sync.WaitGroup
+}
+
+func New(logger *logging.Logger) *Bus {
+	return &Bus{
+		subscribers: make(map[reflect.Type][]*subscriber),
+		logger:      logger,
+	}
+}
+
+// Subscribe runs h for every event of type E before Publish returns.
+func Subscribe[E any](b *Bus, name string, h Handler[E]) {
+	subscribe(b, name, false, h)
+}
+
+// SubscribeAsync runs h for every event of type E in the background. The
+// context passed to h keeps the values of the publisher's but is never
+// cancelled.
+func SubscribeAsync[E any](b *Bus, name string, h Handler[E]) {
+	subscribe(b, name, true, h)
+}
+
+func subscribe[E any](b *Bus, name string, async bool, h Handler[E]) {
+	t := typeOf[E]()
+	s := &subscriber{
+		event: t.String(),
+		name:  name,
+		async: async,
+		handle: func(ctx context.Context, event interface{}) error {
+			return h(ctx, event.(E))
+		},
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers[t] = append(b.subscribers[t], s)
+}
+
+// Publish hands event to the subscribers of its type and returns the errors
+// of the synchronous subscribers, joined.
+func Publish[E any](ctx context.Context, b *Bus, event E) error {
+	b.mu.RLock()
+	subscribers := b.subscribers[typeOf[E]()]
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, s := range subscribers {
+		if !s.async {
+			if err := b.call(ctx, s, event); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", s.name, err))
+			}
+			continue
+		}
+		b.running.Add(1)
+		go func(s *subscriber) {
+			defer b.running.Done()
+			// Asynchronous subscribers outlive the request that published the event.
+			b.call(context.WithoutCancel(ctx), s, event)
+		}(s)
+	}
+	return errors.Join(errs...)
+}
+
+// Wait blocks until the running asynchronous subscribers are done.
+func (b *Bus) Wait() {
+	b.running.Wait()
+}
+
+// Stats lists the statistics of every subscriber, by event and subscriber.
+func (b *Bus) Stats() []Stats {
+	b.mu.RLock()
+	all := make([]Stats, 0)
+	for _, subscribers := range b.subscribers {
+		for _, s := range subscribers {
+			s.mu.Lock()
+			stats := s.stats
+			s.mu.Unlock()
+			stats.Event, stats.Subscriber, stats.Async = s.event, s.name, s.async
+			all = append(all, stats)
+		}
+	}
+	b.mu.RUnlock()
+
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Event != all[j].Event {
+			return all[i].Event < all[j].Event
+		}
+		return all[i].Subscriber < all[j].Subscriber
+	})
+	return all
+}
+
+func (b *Bus) call(ctx context.Context, s *subscriber, event interface{}) error {
+	start := time.Now()
+	err, panicked := invoke(ctx, s, event)
+	latency := time.Since(start)
+
+	s.mu.Lock()
+	s.stats.Calls++
+	s.stats.TotalLatency += latency
+	if latency > s.stats.MaxLatency {
+		s.stats.MaxLatency = latency
+	}
+	if err != nil {
+		s.stats.Errors++
+	}
+	if panicked {
+		s.stats.Panics++
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		b.logger.Errorf("eventbus: %s failed to handle %s: %s", s.name, s.event, err)
+	}
+	return err
+}
+
+func invoke(ctx context.Context, s *subscriber, event interface{}) (err error, panicked bool) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err, panicked = fmt.Errorf("panic: %v\n%s", rec, debug.Stack()), true
+		}
+	}()
+	return s.handle(ctx, event), false
+}
+
+func typeOf[E any]() reflect.Type {
+	return reflect.TypeOf((*E)(nil)).Elem()
+}
@@ -0,0 +1,34 @@
+package httpsign
+
+import (
+	"net/http"
+	"time"
+)
+
+// Transport signs every outgoing request before passing it to Base.
+type Transport struct {
+	KeyID  string
+	Secret []byte
+	Base   http.RoundTripper
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	signed := req.Clone(req.Context())
+	if err := SignRequest(signed, t.KeyID, t.Secret, time.Now()); err != nil {
+		return nil, err
+	}
+	return base.RoundTrip(signed)
+}
+
+// NewClient returns an HTTP client that signs its requests with the given key.
+func NewClient(keyID string, secret []byte, timeout time.Duration) *http.Client {
+	return &http.Client{
+		Transport: &Transport{KeyID: keyID, Secret: secret},
+		Timeout:   timeout,
+	}
+}
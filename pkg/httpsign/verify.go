@@ -0,0 +1,115 @@
+package httpsign
+
+import (
+	"crypto/hmac"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	ErrMissingHeaders   = errors.New("missing signature headers")
+	ErrClockSkew        = errors.New("signature timestamp outside the allowed window")
+	ErrReplay           = errors.New("signature nonce already used")
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+// NonceStore remembers nonces until they expire. Seen records the nonce
+// and reports whether it was already present.
+type NonceStore interface {
+	Seen(nonce string, expires time.Time) bool
+}
+
+type Verifier struct {
+	skew   time.Duration
+	nonces NonceStore
+}
+
+// NewVerifier accepts timestamps within skew of the local clock. Nonces are
+// kept for twice the skew, the whole window a request could be replayed in.
+func NewVerifier(skew time.Duration, nonces NonceStore) *Verifier {
+	return &Verifier{
+		skew:   skew,
+		nonces: nonces,
+	}
+}
+
+func KeyID(r *http.Request) string {
+	return r.Header.Get(HeaderKeyID)
+}
+
+func IsSigned(r *http.Request) bool {
+	return r.Header.Get(HeaderSignature) != ""
+}
+
+// Verify checks the signature of r made with secret. The body is read and
+// replaced so handlers can still consume it.
+func (v *Verifier) Verify(r *http.Request, secret []byte, now time.Time) error {
+	keyID := r.Header.Get(HeaderKeyID)
+	timestamp := r.Header.Get(HeaderTimestamp)
+	nonce := r.Header.Get(HeaderNonce)
+	signature := r.Header.Get(HeaderSignature)
+	if keyID == "" || timestamp == "" || nonce == "" || signature == "" {
+		return ErrMissingHeaders
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrClockSkew
+	}
+	signedAt := time.Unix(ts, 0)
+	if signedAt.Before(now.Add(-v.skew)) || signedAt.After(now.Add(v.skew)) {
+		return ErrClockSkew
+	}
+
+	body, err := readBody(r)
+	if err != nil {
+		return err
+	}
+
+	expected := Signature(secret, StringToSign(r.Method, r.URL.RequestURI(), timestamp, nonce, body))
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+
+	// Only valid signatures consume a nonce, so forged requests cannot
+	// poison the cache for the legitimate caller.
+	if v.nonces.Seen(keyID+":"+nonce, now.Add(2*v.skew)) {
+		return ErrReplay
+	}
+
+	return nil
+}
+
+type memoryNonceStore struct {
+	mu        sync.Mutex
+	nonces    map[string]time.Time
+	lastSweep time.Time
+}
+
+func NewMemoryNonceStore() NonceStore {
+	return &memoryNonceStore{nonces: make(map[string]time.Time)}
+}
+
+func (s *memoryNonceStore) Seen(nonce string, expires time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) > time.Minute {
+		for n, exp := range s.nonces {
+			if now.After(exp) {
+				delete(s.nonces, n)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	if exp, ok := s.nonces[nonce]; ok && now.Before(exp) {
+		return true
+	}
+	s.nonces[nonce] = expires
+	return false
+}
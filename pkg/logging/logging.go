@@ -43,6 +43,15 @@ func (l *Logger) GetLoggerWithField(k string, v interface{}) *Logger {
 	return &Logger{l.WithField(k, v)}
 }
 
+func SetLevel(level string) error {
+	lvl, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	e.Logger.SetLevel(lvl)
+	return nil
+}
+
 func init() {
 	l := logrus.New()
 	l.SetReportCaller(true)
@@ -0,0 +1,28 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limit describes a token bucket refilled at Rate tokens per second up to Burst.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is the time until the next request is allowed, zero when allowed.
+	RetryAfter time.Duration
+}
+
+// Limiter takes one token for key. Implementations may share state between
+// instances, e.g. through Redis, as long as they honour the Limit passed in.
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
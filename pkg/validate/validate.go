@@ -0,0 +1,168 @@
+// Package validate checks struct fields against rules declared in a
+// `validate` tag, e.g. `validate:"required,max=64"`.
+//
+// Supported rules are required, min=N and max=N (length for strings and
+// slices, value for numbers) and oneof=a b c. Nested structs are validated
+// too, their errors are reported as parent.child.
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+const TagName = "validate"
+
+type Rule struct {
+	Name  string
+	Value string
+}
+
+// Rules parses a validate tag.
+func Rules(tag string) []Rule {
+	if tag == "" || tag == "-" {
+		return nil
+	}
+	var rules []Rule
+	for _, part := range strings.Split(tag, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		rules = append(rules, Rule{Name: name, Value: value})
+	}
+	return rules
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// Struct validates the exported fields of v, which must be a struct or a
+// pointer to one. Field names in errors follow the json tag.
+func Struct(v interface{}) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("validate: %T is not a struct", v)
+	}
+	return errors.Join(fields(rv, "")...)
+}
+
+func fields(rv reflect.Value, prefix string) []error {
+	var errs []error
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := prefix + FieldName(field)
+		failed := false
+		for _, rule := range Rules(field.Tag.Get(TagName)) {
+			if msg := check(rv.Field(i), rule); msg != "" {
+				errs = append(errs, FieldError{Field: name, Message: msg})
+				failed = true
+				break
+			}
+		}
+
+		nested := reflect.Indirect(rv.Field(i))
+		if !failed && nested.Kind() == reflect.Struct && nested.Type() != timeType {
+			if field.Anonymous && field.Tag.Get("json") == "" {
+				errs = append(errs, fields(nested, prefix)...)
+			} else {
+				errs = append(errs, fields(nested, name+".")...)
+			}
+		}
+	}
+	return errs
+}
+
+// Fields returns the individual field errors joined in err.
+func Fields(err error) []FieldError {
+	var fields []FieldError
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			fields = append(fields, Fields(e)...)
+		}
+		return fields
+	}
+	var fe FieldError
+	if errors.As(err, &fe) {
+		fields = append(fields, fe)
+	}
+	return fields
+}
+
+// FieldName returns the json name of a struct field.
+func FieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+func check(v reflect.Value, rule Rule) string {
+	switch rule.Name {
+	case "required":
+		if v.IsZero() {
+			return "is required"
+		}
+	case "min", "max":
+		limit, err := strconv.ParseFloat(rule.Value, 64)
+		if err != nil {
+			return "has an invalid " + rule.Name + " rule"
+		}
+		n, unit, ok := measure(v)
+		if !ok {
+			return ""
+		}
+		if rule.Name == "min" && n < limit {
+			return fmt.Sprintf("must be at least %s%s", rule.Value, unit)
+		}
+		if rule.Name == "max" && n > limit {
+			return fmt.Sprintf("must be at most %s%s", rule.Value, unit)
+		}
+	case "oneof":
+		if v.Kind() == reflect.String && v.String() != "" {
+			for _, allowed := range strings.Fields(rule.Value) {
+				if v.String() == allowed {
+					return ""
+				}
+			}
+			return "must be one of " + strings.Join(strings.Fields(rule.Value), ", ")
+		}
+	}
+	return ""
+}
+
+func measure(v reflect.Value) (float64, string, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), " characters", true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), " items", true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), "", true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), "", true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), "", true
+	case reflect.Ptr:
+		if v.IsNil() {
+			return 0, "", false
+		}
+		return measure(v.Elem())
+	}
+	return 0, "", false
+}